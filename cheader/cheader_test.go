@@ -0,0 +1,230 @@
+package cheader
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/buarki/viztruct/structi"
+)
+
+func analyse(t *testing.T, input string) []structi.Info {
+	t.Helper()
+	infos, err := structi.AnalyseStructs(input)
+	if err != nil {
+		t.Fatalf("analyse error: %v", err)
+	}
+	return infos
+}
+
+const shared = `package main
+
+type Inner struct {
+	A int32
+	B int64
+}
+
+type Header struct {
+	Magic uint32
+	Size  uintptr
+	Flags [3]uint16
+	_     byte
+	Next  *Header
+	In    Inner
+	Ratio float64
+	OK    bool
+}
+`
+
+func TestExportRoundTrip(t *testing.T) {
+	infos := analyse(t, shared)
+	header := Export(infos, "amd64")
+
+	for _, want := range []string{
+		"\tuint32_t Magic;\n\tuint8_t _pad0[4];\n\tuintptr_t Size;\n",
+		"\tuint16_t Flags[3];\n",
+		"\t_Alignas(8) uint8_t In[16] /* temp.Inner */;\n",
+		`_Static_assert(offsetof(struct Header, Ratio) == 48, "Header.Ratio offset");`,
+	} {
+		if !strings.Contains(header, want) {
+			t.Errorf("missing %q in\n%s", want, header)
+		}
+	}
+
+	parsed, err := Parse(header, "amd64")
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	if len(parsed) != 2 {
+		t.Fatalf("expected 2 structs, got %d", len(parsed))
+	}
+	for i := range infos {
+		if mismatches := Compare(infos[i], parsed[i]); len(mismatches) != 0 {
+			t.Errorf("exported %s differs: %v", infos[i].Name, mismatches)
+		}
+	}
+
+	cc, err := exec.LookPath("cc")
+	if err != nil {
+		t.Skip("no C compiler to check the static assertions")
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "layout.c")
+	if err := os.WriteFile(path, []byte(header), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := exec.Command(cc, "-std=c11", "-fsyntax-only", path).CombinedOutput(); err != nil {
+		t.Errorf("exported header does not compile: %v\n%s", err, out)
+	}
+}
+
+func TestExportBlankFields(t *testing.T) {
+	infos := analyse(t, `package main
+
+type Aligned struct {
+	_ int64
+	A int32
+}
+`)
+	header := Export(infos, "amd64")
+	if !strings.Contains(header, "\tint64_t _pad0;\n\tint32_t A;\n") {
+		t.Errorf("blank field lost its type in\n%s", header)
+	}
+
+	parsed, err := Parse(header, "amd64")
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	if parsed[0].OriginalSize != 16 {
+		t.Errorf("exported struct is %d bytes, want 16", parsed[0].OriginalSize)
+	}
+
+	cc, err := exec.LookPath("cc")
+	if err != nil {
+		t.Skip("no C compiler to check the static assertions")
+	}
+	path := filepath.Join(t.TempDir(), "layout.c")
+	if err := os.WriteFile(path, []byte(header), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := exec.Command(cc, "-std=c11", "-fsyntax-only", path).CombinedOutput(); err != nil {
+		t.Errorf("exported header does not compile: %v\n%s", err, out)
+	}
+}
+
+func TestParseTypedefTag(t *testing.T) {
+	infos, err := Parse(`typedef struct point {
+	int x;
+	int y;
+} point_t;
+
+struct segment {
+	struct point from;
+	point_t to;
+	char tag;
+};
+`, "amd64")
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	if len(infos) != 2 || infos[0].Name != "point_t" {
+		t.Fatalf("unexpected structs %+v", infos)
+	}
+	if size := infos[1].OriginalSize; size != 20 {
+		t.Errorf("segment is %d bytes, want 20", size)
+	}
+}
+
+func TestParse(t *testing.T) {
+	src := `#include <stdint.h>
+
+/* a header */
+struct point {
+	int x, y_unused;
+};
+
+typedef struct node {
+	const unsigned long int id; // word sized
+	const char * const name;
+	signed short kind;
+	double weights[2][2];
+	struct point at;
+} Node;
+`
+	_, err := Parse(src, "amd64")
+	if err == nil {
+		t.Fatalf("declaring several members at once is not supported and should fail")
+	}
+
+	src = strings.Replace(src, "int x, y_unused;", "int x;\n\tint y;", 1)
+	for arch, want := range map[string][]int64{
+		// offsets of id, name, kind, weights, at and the size
+		"amd64": {0, 8, 16, 24, 56, 64},
+		"386":   {0, 4, 8, 12, 44, 52},
+	} {
+		infos, err := Parse(src, arch)
+		if err != nil {
+			t.Fatalf("parse error on %s: %v", arch, err)
+		}
+		if len(infos) != 2 || infos[1].Name != "Node" {
+			t.Fatalf("unexpected structs %+v", infos)
+		}
+
+		var got []int64
+		for _, f := range infos[1].Fields {
+			if !f.IsPadding {
+				got = append(got, f.Offset)
+			}
+		}
+		got = append(got, infos[1].OriginalSize)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got offsets %v, want %v", arch, got, want)
+		}
+		if name := infos[1].Fields[1]; name.Name != "name" || name.TypeName != "char *" {
+			t.Errorf("%s: unexpected field %+v", arch, name)
+		}
+	}
+
+	if _, err := Parse("struct flags { unsigned a : 1; };", "amd64"); err == nil {
+		t.Errorf("bit fields should not be supported")
+	}
+}
+
+func TestCompare(t *testing.T) {
+	goInfo := analyse(t, `package main
+
+type Header struct {
+	Magic uint32
+	Size  uint64
+	Kind  uint16
+}
+`)[0]
+
+	cInfos, err := Parse(`struct header {
+	uint32_t magic;
+	uint32_t size;
+	uint8_t _pad[2];
+	uint16_t flags;
+};`, "amd64")
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+
+	var got []string
+	for _, m := range Compare(goInfo, cInfos[0]) {
+		got = append(got, m.Message)
+	}
+	want := []string{
+		"size is 24 bytes in Go and 12 in C",
+		"field Size is at offset 8 in Go and 4 in C",
+		"field Size is 8 bytes in Go (uint64) and 4 in C (uint32_t)",
+		"field Kind (uint16) has no C member",
+		"C member flags (uint16_t) at offset 10 has no Go field",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got\n%v\nwant\n%v", got, want)
+	}
+}
@@ -0,0 +1,76 @@
+package cheader
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/buarki/viztruct/structi"
+)
+
+// Mismatch is a difference between the layout of a Go struct and the C
+// struct it is shared with.
+type Mismatch struct {
+	Struct  string `json:"struct"`
+	Message string `json:"message"`
+}
+
+// Compare checks a Go struct against a C struct field by field. Fields are
+// matched by name ignoring case, C members named like padding (_pad0,
+// reserved...) and blank Go fields only occupy space.
+func Compare(goInfo, cInfo structi.Info) []Mismatch {
+	var mismatches []Mismatch
+	add := func(format string, args ...any) {
+		mismatches = append(mismatches, Mismatch{Struct: goInfo.Name, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if goInfo.OriginalSize != cInfo.OriginalSize {
+		add("size is %d bytes in Go and %d in C", goInfo.OriginalSize, cInfo.OriginalSize)
+	}
+
+	members := make(map[string]structi.Field)
+	for _, f := range cInfo.Fields {
+		if !f.IsPadding {
+			members[strings.ToLower(f.Name)] = f
+		}
+	}
+
+	matched := make(map[string]bool)
+	for _, f := range goInfo.Fields {
+		if f.IsPadding || f.Name == "_" {
+			continue
+		}
+		key := strings.ToLower(f.Name)
+		c, ok := members[key]
+		if !ok {
+			add("field %s (%s) has no C member", f.Name, f.TypeName)
+			continue
+		}
+		matched[key] = true
+
+		if f.Offset != c.Offset {
+			add("field %s is at offset %d in Go and %d in C", f.Name, f.Offset, c.Offset)
+		}
+		if f.Size != c.Size {
+			add("field %s is %d bytes in Go (%s) and %d in C (%s)", f.Name, f.Size, f.TypeName, c.Size, c.TypeName)
+		}
+	}
+
+	for _, c := range cInfo.Fields {
+		if c.IsPadding || matched[strings.ToLower(c.Name)] || paddingMember(c.Name) {
+			continue
+		}
+		add("C member %s (%s) at offset %d has no Go field", c.Name, c.TypeName, c.Offset)
+	}
+
+	return mismatches
+}
+
+func paddingMember(name string) bool {
+	name = strings.TrimLeft(strings.ToLower(name), "_")
+	for _, prefix := range []string{"pad", "reserved", "unused"} {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
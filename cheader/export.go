@@ -0,0 +1,111 @@
+package cheader
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/buarki/viztruct/structi"
+)
+
+var scalarTypes = map[string]string{
+	"bool":    "_Bool",
+	"int8":    "int8_t",
+	"int16":   "int16_t",
+	"int32":   "int32_t",
+	"int64":   "int64_t",
+	"uint8":   "uint8_t",
+	"uint16":  "uint16_t",
+	"uint32":  "uint32_t",
+	"uint64":  "uint64_t",
+	"byte":    "uint8_t",
+	"rune":    "int32_t",
+	"uintptr": "uintptr_t",
+	"float32": "float",
+	"float64": "double",
+}
+
+var arrayType = regexp.MustCompile(`^\[(\d+)\](.+)$`)
+
+// Export writes the structs as C declarations laying their fields out at
+// the same offsets, with the padding made explicit, followed by
+// _Static_assert checks of their size and offsets so the C compiler
+// rejects the header on an architecture where the layouts disagree.
+func Export(infos []structi.Info, arch string) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "// Code generated by \"viztruct cheader\"; DO NOT EDIT.\n")
+	fmt.Fprintf(&sb, "// Struct layouts on %s.\n\n", arch)
+	sb.WriteString("#include <stddef.h>\n#include <stdint.h>\n")
+
+	for _, info := range infos {
+		fmt.Fprintf(&sb, "\nstruct %s {\n", info.Name)
+		pad := 0
+		for _, f := range info.Fields {
+			switch {
+			case f.IsPadding:
+				fmt.Fprintf(&sb, "\tuint8_t _pad%d[%d];\n", pad, f.Size)
+				pad++
+				continue
+			case f.Name == "_":
+				// blank fields keep their type, and so their alignment
+				f.Name = fmt.Sprintf("_pad%d", pad)
+				pad++
+			}
+			fmt.Fprintf(&sb, "\t%s;\n", declaration(f))
+		}
+		sb.WriteString("};\n\n")
+
+		fmt.Fprintf(&sb, "_Static_assert(sizeof(struct %s) == %d, \"%s size\");\n", info.Name, info.OriginalSize, info.Name)
+		for _, f := range info.Fields {
+			if f.IsPadding || f.Name == "_" {
+				continue
+			}
+			fmt.Fprintf(&sb, "_Static_assert(offsetof(struct %s, %s) == %d, \"%s.%s offset\");\n",
+				info.Name, f.Name, f.Offset, info.Name, f.Name)
+		}
+	}
+
+	return sb.String()
+}
+
+// declaration is the C member standing for a Go field. Types without a C
+// equivalent become an aligned byte array of the same size.
+func declaration(f structi.Field) string {
+	if decl, ok := cDeclaration(f.TypeName, f.Name, f.Size); ok {
+		return decl
+	}
+	return fmt.Sprintf("_Alignas(%d) uint8_t %s[%d] /* %s */", f.Align, f.Name, f.Size, f.TypeName)
+}
+
+func cDeclaration(goType, name string, size int64) (string, bool) {
+	if c, ok := scalarTypes[goType]; ok {
+		return c + " " + name, true
+	}
+
+	switch {
+	case goType == "int" || goType == "uint":
+		c := fmt.Sprintf("int%d_t", size*8)
+		if goType == "uint" {
+			c = "u" + c
+		}
+		return c + " " + name, true
+	case goType == "complex64":
+		return "float " + name + "[2]", true
+	case goType == "complex128":
+		return "double " + name + "[2]", true
+	case goType == "unsafe.Pointer", strings.HasPrefix(goType, "*"), strings.HasPrefix(goType, "map["),
+		strings.HasPrefix(goType, "chan "), strings.HasPrefix(goType, "func("):
+		return "void *" + name, true
+	}
+
+	if m := arrayType.FindStringSubmatch(goType); m != nil {
+		var n int64
+		fmt.Sscan(m[1], &n)
+		if n == 0 {
+			return "", false
+		}
+		// the element keeps the rest of the declarator: [2][3]T is T name[2][3]
+		return cDeclaration(m[2], name+"["+m[1]+"]", size/n)
+	}
+	return "", false
+}
@@ -0,0 +1,229 @@
+package cheader
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/buarki/viztruct/structi"
+)
+
+var (
+	blockComment = regexp.MustCompile(`(?s)/\*.*?\*/`)
+	lineComment  = regexp.MustCompile(`//[^\n]*`)
+	preprocessor = regexp.MustCompile(`(?m)^\s*#.*$`)
+	// struct Name { or typedef struct [Name] {
+	structStart = regexp.MustCompile(`(typedef\s+)?struct\s*(\w*)\s*\{`)
+	alignas     = regexp.MustCompile(`_Alignas\s*\(\s*(\d+)\s*\)`)
+	// type, pointers, name and array lengths of a member, pointers may be
+	// qualified themselves as in "const char * const p"
+	declarator  = regexp.MustCompile(`(?s)^(.*?)\s*((?:\*\s*(?:(?:const|volatile|restrict)\b\s*)*)*)(\w+)\s*((?:\[\s*\d+\s*\]\s*)*)$`)
+	arrayLength = regexp.MustCompile(`\[\s*(\d+)\s*\]`)
+)
+
+// cModel holds the sizes and alignments of the C types on an architecture,
+// following the System V ABIs: 8 bytes scalars are only 4 bytes aligned on
+// 386 and long is as wide as a pointer.
+type cModel struct {
+	word    int64
+	align8  int64
+	structs map[string]structi.Info
+}
+
+func newModel(arch string) (*cModel, error) {
+	m := &cModel{word: 8, align8: 8, structs: make(map[string]structi.Info)}
+	switch arch {
+	case "386", "arm", "mips", "mipsle", "wasm32":
+		m.word = 4
+	case "amd64", "arm64", "loong64", "mips64", "mips64le", "ppc64", "ppc64le", "riscv64", "s390x", "wasm":
+	default:
+		return nil, fmt.Errorf("unsupported architecture: %s", arch)
+	}
+	if arch == "386" {
+		m.align8 = 4
+	}
+	return m, nil
+}
+
+func (m *cModel) scalar(name string) (int64, bool) {
+	switch name {
+	case "char", "signed char", "unsigned char", "int8_t", "uint8_t", "_Bool", "bool":
+		return 1, true
+	case "short", "unsigned short", "int16_t", "uint16_t":
+		return 2, true
+	case "int", "unsigned", "unsigned int", "int32_t", "uint32_t", "float":
+		return 4, true
+	case "long long", "unsigned long long", "int64_t", "uint64_t", "double":
+		return 8, true
+	case "long", "unsigned long", "size_t", "ssize_t", "intptr_t", "uintptr_t", "ptrdiff_t":
+		return m.word, true
+	}
+	return 0, false
+}
+
+// Parse reads the struct declarations of a simple C header: members of
+// scalar, pointer, array and previously declared struct types. Bit fields
+// and unions are not supported. Typedef'd structs are named after their
+// typedef, members may refer to them by their tag as well.
+func Parse(src string, arch string) ([]structi.Info, error) {
+	m, err := newModel(arch)
+	if err != nil {
+		return nil, err
+	}
+
+	src = blockComment.ReplaceAllString(src, " ")
+	src = lineComment.ReplaceAllString(src, "")
+	src = preprocessor.ReplaceAllString(src, "")
+
+	var infos []structi.Info
+	for {
+		loc := structStart.FindStringSubmatchIndex(src)
+		if loc == nil {
+			break
+		}
+		typedef := loc[2] >= 0
+		name := src[loc[4]:loc[5]]
+		tag := name
+
+		end := strings.Index(src[loc[1]:], "}")
+		if end < 0 {
+			return nil, fmt.Errorf("struct %s is not closed", name)
+		}
+		body := src[loc[1] : loc[1]+end]
+		src = src[loc[1]+end+1:]
+
+		if typedef {
+			alias, rest, ok := strings.Cut(src, ";")
+			if !ok || strings.TrimSpace(alias) == "" {
+				return nil, fmt.Errorf("typedef of struct %s has no name", name)
+			}
+			name, src = strings.TrimSpace(alias), rest
+		}
+		if name == "" {
+			return nil, fmt.Errorf("anonymous structs are not supported")
+		}
+
+		info, err := m.layout(name, body)
+		if err != nil {
+			return nil, fmt.Errorf("struct %s: %v", name, err)
+		}
+		m.structs[name] = info
+		m.structs["struct "+name] = info
+		// members usually refer to typedef'd structs by their tag
+		if tag != "" {
+			m.structs["struct "+tag] = info
+		}
+		infos = append(infos, info)
+	}
+
+	return infos, nil
+}
+
+func (m *cModel) layout(name, body string) (structi.Info, error) {
+	var fields []structi.Field
+	var offset, structAlign int64 = 0, 1
+
+	for _, member := range strings.Split(body, ";") {
+		member = strings.TrimSpace(member)
+		if member == "" {
+			continue
+		}
+		if strings.Contains(member, ":") {
+			return structi.Info{}, fmt.Errorf("bit fields are not supported: %s", member)
+		}
+
+		var forcedAlign int64
+		if a := alignas.FindStringSubmatch(member); a != nil {
+			forcedAlign, _ = strconv.ParseInt(a[1], 10, 64)
+			member = alignas.ReplaceAllString(member, "")
+		}
+
+		f, err := m.member(member)
+		if err != nil {
+			return structi.Info{}, err
+		}
+		f.Align = max(f.Align, forcedAlign)
+
+		if rem := offset % f.Align; rem != 0 {
+			fields = append(fields, structi.Field{Name: "padding", Offset: offset, Size: f.Align - rem, Align: 1, IsPadding: true})
+			offset += f.Align - rem
+		}
+		f.Offset = offset
+		offset += f.Size
+		structAlign = max(structAlign, f.Align)
+		fields = append(fields, f)
+	}
+
+	if rem := offset % structAlign; rem != 0 {
+		fields = append(fields, structi.Field{Name: "padding", Offset: offset, Size: structAlign - rem, Align: 1, IsPadding: true})
+		offset += structAlign - rem
+	}
+
+	info := structi.Info{Name: name, OriginalSize: offset, Fields: fields}
+	for _, f := range fields {
+		if f.IsPadding {
+			info.WastedBytes += f.Size
+		}
+	}
+	if offset > 0 {
+		info.WastedPercent = float64(info.WastedBytes) / float64(offset) * 100
+	}
+	return info, nil
+}
+
+func (m *cModel) member(member string) (structi.Field, error) {
+	d := declarator.FindStringSubmatch(member)
+	if d == nil {
+		return structi.Field{}, fmt.Errorf("cannot parse member %q", member)
+	}
+	typeName, name, arrays := normalizeType(d[1]), d[3], d[4]
+	stars := strings.Repeat("*", strings.Count(d[2], "*"))
+
+	var size, align int64
+	if stars != "" {
+		size, align = m.word, m.word
+		typeName += " " + stars
+	} else if s, ok := m.scalar(typeName); ok {
+		size, align = s, min(s, m.align8)
+	} else if nested, ok := m.structs[typeName]; ok {
+		size, align = nested.OriginalSize, nested.Align()
+	} else {
+		return structi.Field{}, fmt.Errorf("unknown type %q", typeName)
+	}
+
+	for _, n := range arrayLength.FindAllStringSubmatch(arrays, -1) {
+		length, _ := strconv.ParseInt(n[1], 10, 64)
+		size *= length
+		typeName += "[" + n[1] + "]"
+	}
+
+	return structi.Field{Name: name, TypeName: typeName, Size: size, Align: align}, nil
+}
+
+// normalizeType drops qualifiers and spells integer types one way:
+// "const unsigned long int" is "unsigned long".
+func normalizeType(t string) string {
+	var words []string
+	for _, w := range strings.Fields(t) {
+		switch w {
+		case "const", "volatile", "restrict":
+			continue
+		}
+		words = append(words, w)
+	}
+
+	joined := strings.Join(words, " ")
+	if strings.HasPrefix(joined, "signed ") && joined != "signed char" {
+		joined = strings.TrimPrefix(joined, "signed ")
+	}
+	if joined == "signed" {
+		joined = "int"
+	}
+	for _, prefix := range []string{"short", "unsigned short", "long", "unsigned long", "long long", "unsigned long long"} {
+		if joined == prefix+" int" {
+			joined = prefix
+		}
+	}
+	return joined
+}
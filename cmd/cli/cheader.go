@@ -0,0 +1,100 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+
+	"github.com/buarki/viztruct/cheader"
+	"github.com/buarki/viztruct/structi"
+)
+
+func runCHeader(args []string) {
+	fs := flag.NewFlagSet("cheader", flag.ExitOnError)
+	archFlag := fs.String("arch", runtime.GOARCH, "Comma separated architectures, exporting uses the first one")
+	typesFlag := fs.String("types", "", "Comma separated structs to export besides the ones marked //viztruct:stable")
+	compareFlag := fs.String("compare", "", "C header whose structs are compared with the Go structs of the same name")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s cheader [options] [packages]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Prints the selected structs as C declarations with explicit padding and\n")
+		fmt.Fprintf(os.Stderr, "_Static_assert checks. With --compare, checks the structs of a C header\n")
+		fmt.Fprintf(os.Stderr, "against the Go structs of the same name instead.\n\nOptions:\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	archs := splitList(*archFlag)
+	if len(archs) == 0 {
+		fs.Usage()
+		os.Exit(exitError)
+	}
+
+	if *compareFlag != "" {
+		compareCHeader(*compareFlag, archs, fs.Args(), splitList(*typesFlag))
+		return
+	}
+
+	var selected []structi.Info
+	for _, sel := range selectForGen(archs[0], fs.Args(), splitList(*typesFlag)) {
+		selected = append(selected, sel.structs...)
+	}
+	if len(selected) == 0 {
+		fmt.Fprintf(os.Stderr, "no struct selected: mark them with //viztruct:stable or use --types\n")
+		os.Exit(exitError)
+	}
+	fmt.Print(cheader.Export(selected, archs[0]))
+}
+
+func compareCHeader(path string, archs []string, patterns []string, types []string) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error reading C header: %v\n", err)
+		os.Exit(exitError)
+	}
+
+	wanted := make(map[string]bool)
+	for _, t := range types {
+		wanted[strings.ToLower(t)] = true
+	}
+
+	var mismatches []cheader.Mismatch
+	byArch := analyzeByArch(archs, patterns)
+	for _, arch := range archs {
+		structs := byArch[arch]
+		cStructs, err := cheader.Parse(string(src), arch)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error parsing %s: %v\n", path, err)
+			os.Exit(exitError)
+		}
+
+		goStructs := make(map[string]structi.Info)
+		for _, s := range structs {
+			goStructs[strings.ToLower(s.Name)] = s
+		}
+
+		for _, c := range cStructs {
+			key := strings.ToLower(c.Name)
+			if len(wanted) > 0 && !wanted[key] {
+				continue
+			}
+			g, ok := goStructs[key]
+			if !ok {
+				fmt.Fprintf(os.Stderr, "C struct %s has no Go struct of the same name, skipped\n", c.Name)
+				continue
+			}
+			for _, m := range cheader.Compare(g, c) {
+				m.Message = fmt.Sprintf("%s (%s)", m.Message, arch)
+				mismatches = append(mismatches, m)
+			}
+		}
+	}
+
+	for _, m := range mismatches {
+		fmt.Printf("%s: %s\n", m.Struct, m.Message)
+	}
+	if len(mismatches) > 0 {
+		os.Exit(exitFindings)
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/buarki/viztruct/diff"
+	"github.com/buarki/viztruct/structi"
+	"github.com/buarki/viztruct/svg"
+)
+
+const diffSVGFile = "struct-diff.svg"
+
+func runDiff(args []string) {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	format := fs.String("format", string(FormatText), "Output format (txt, json, markdown)")
+	generateSVG := fs.Bool("svg", false, "Generate a side by side SVG of the changes into "+diffSVGFile)
+	svgWidth := fs.Float64("svg-width", 1200, "Width of the SVG in pixels")
+	svgThemeFlag := fs.String("svg-theme", "light", "SVG theme: "+strings.Join(svg.ThemeNames(), ", "))
+	svgPalette := fs.String("svg-palette", "", "JSON palette file overriding the colors of the theme")
+	svgCSSFlag := fs.String("svg-css", "", "CSS file appended to the SVG style sheet")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s diff [options] <rev1> <rev2> [packages]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Compares the struct layouts of the packages between two git revisions.\n\nOptions:\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	outputFormat := OutputFormat(*format)
+	if outputFormat != FormatJSON && outputFormat != FormatText && outputFormat != FormatMarkdown {
+		fmt.Fprintf(os.Stderr, "invalid format: %s. use 'txt', 'json' or 'markdown'\n", *format)
+		os.Exit(exitError)
+	}
+
+	svgTheme, svgCSS := readSVGStyle(*svgThemeFlag, *svgPalette, *svgCSSFlag)
+
+	if fs.NArg() < 2 {
+		fs.Usage()
+		os.Exit(exitError)
+	}
+	before, after, patterns := fs.Arg(0), fs.Arg(1), fs.Args()[2:]
+
+	changes := diff.Compare(analyzeRevision(before, patterns), analyzeRevision(after, patterns))
+
+	if *generateSVG {
+		svgOutput, err := svg.BuildDiffVisualization(changes, before, after, svg.Options{Width: *svgWidth, Theme: svgTheme, CSS: svgCSS})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error building SVG: %v\n", err)
+			os.Exit(exitError)
+		}
+		if err := os.WriteFile(diffSVGFile, []byte(svgOutput), 0644); err != nil {
+			fmt.Fprintf(os.Stderr, "error writing svg file: %v\n", err)
+			os.Exit(exitError)
+		}
+	}
+
+	switch outputFormat {
+	case FormatJSON:
+		jsonOutput, err := json.MarshalIndent(changes, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error encoding json: %v\n", err)
+			os.Exit(exitError)
+		}
+		fmt.Println(string(jsonOutput))
+	case FormatMarkdown:
+		fmt.Print(markdownDiff(changes, before, after))
+	default:
+		fmt.Printf("%d structs changed between %s and %s\n", len(changes), before, after)
+		for _, c := range changes {
+			fmt.Printf("\n%s: %s\n", c.Struct, c.Summary())
+			for _, f := range c.Fields {
+				fmt.Printf("  %s\n", f)
+			}
+		}
+	}
+}
+
+// analyzeRevision checks rev out into a temporary worktree and analyses
+// the packages as they were at that revision.
+func analyzeRevision(rev string, patterns []string) []structi.Info {
+	cwd, err := os.Getwd()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(exitError)
+	}
+
+	dir, cleanup, err := diff.Worktree(cwd, rev)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error checking out %s: %v\n", rev, err)
+		os.Exit(exitError)
+	}
+	defer cleanup()
+
+	pkgs, err := structi.LoadPackages(structi.LoadConfig{Dir: dir}, patterns...)
+	if err != nil {
+		cleanup()
+		fmt.Fprintf(os.Stderr, "error loading packages at %s: %v\n", rev, err)
+		os.Exit(exitError)
+	}
+
+	structs, err := structi.AnalysePackages(pkgs)
+	if err != nil {
+		cleanup()
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(exitError)
+	}
+
+	// positions inside the temporary worktree are meaningless once removed
+	for i := range structs {
+		if rel, err := filepath.Rel(dir, structs[i].File); err == nil {
+			structs[i].File = rel
+		}
+	}
+	return structs
+}
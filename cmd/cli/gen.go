@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/buarki/viztruct/gen"
+	"github.com/buarki/viztruct/lock"
+	"github.com/buarki/viztruct/structi"
+)
+
+func runAsserts(args []string) {
+	fs := flag.NewFlagSet("asserts", flag.ExitOnError)
+	archFlag := fs.String("arch", runtime.GOARCH, "Comma separated architectures to generate assertions for")
+	typesFlag := fs.String("types", "", "Comma separated structs to assert besides the ones marked //viztruct:stable")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s asserts [options] [packages]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Generates a viztruct_layout_<arch>.go file per package failing to compile\n")
+		fmt.Fprintf(os.Stderr, "when the size or field offsets of the selected structs change.\n\nOptions:\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	generated := 0
+	for _, arch := range splitList(*archFlag) {
+		for _, sel := range selectForGen(arch, fs.Args(), splitList(*typesFlag)) {
+			src, err := gen.Assertions(sel.pkg.Name, arch, sel.structs)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%v\n", err)
+				os.Exit(exitError)
+			}
+
+			path := filepath.Join(sel.pkg.Dir, gen.AssertsFile(arch))
+			if err := os.WriteFile(path, src, 0644); err != nil {
+				fmt.Fprintf(os.Stderr, "error writing assertions: %v\n", err)
+				os.Exit(exitError)
+			}
+			fmt.Printf("wrote %s (%d structs)\n", path, len(sel.structs))
+			generated++
+		}
+	}
+
+	if generated == 0 {
+		fmt.Fprintf(os.Stderr, "no struct selected: mark them with //viztruct:stable or use --types\n")
+		os.Exit(exitError)
+	}
+}
+
+type genSelection struct {
+	pkg     *structi.Package
+	structs []structi.Info
+}
+
+// selectForGen loads the packages for arch without the generated files and
+// returns, per package, the selected structs declared at package level.
+func selectForGen(arch string, patterns []string, types []string) []genSelection {
+	selections := loadForGen(arch, patterns)
+	for i := range selections {
+		selections[i].structs = lock.Select(selections[i].structs, types)
+	}
+
+	var selected []genSelection
+	for _, sel := range selections {
+		if len(sel.structs) > 0 {
+			selected = append(selected, sel)
+		}
+	}
+	return selected
+}
+
+// loadForGen loads the packages for arch without the generated files and
+// returns the structs declared at package level of each of them.
+func loadForGen(arch string, patterns []string) []genSelection {
+	pkgs, err := structi.LoadPackages(structi.LoadConfig{GOARCH: arch, Tags: []string{gen.IgnoreTag}}, patterns...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error loading packages for %s: %v\n", arch, err)
+		os.Exit(exitError)
+	}
+
+	structs, err := structi.AnalysePackages(pkgs)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(exitError)
+	}
+
+	byPackage := make(map[string][]structi.Info)
+	for _, s := range structs {
+		byPackage[s.Package] = append(byPackage[s.Package], s)
+	}
+
+	var selections []genSelection
+	for _, pkg := range pkgs {
+		var declared []structi.Info
+		for _, s := range byPackage[pkg.Path] {
+			// types local to functions cannot be named from another file
+			if pkg.Types.Scope().Lookup(s.Name) != nil {
+				declared = append(declared, s)
+			}
+		}
+		selections = append(selections, genSelection{pkg: pkg, structs: declared})
+	}
+	return selections
+}
+
+func runAsmHeader(args []string) {
+	fs := flag.NewFlagSet("asmhdr", flag.ExitOnError)
+	archFlag := fs.String("arch", runtime.GOARCH, "Comma separated architectures to generate headers for")
+	typesFlag := fs.String("types", "", "Comma separated structs to export besides the ones marked //viztruct:stable")
+	checkFlag := fs.Bool("check", false, "Check the assembly files and headers instead of generating headers")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s asmhdr [options] [packages]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Generates a viztruct_asm_<arch>.h header per package with T__size and\n")
+		fmt.Fprintf(os.Stderr, "T_field constants. With --check, reports numeric offsets of .s files\n")
+		fmt.Fprintf(os.Stderr, "commented with the field they access (8(AX) // T.field) that no longer\n")
+		fmt.Fprintf(os.Stderr, "match, and headers that are out of date.\n\nOptions:\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if *checkFlag {
+		checkAsm(splitList(*archFlag), fs.Args(), splitList(*typesFlag))
+		return
+	}
+
+	generated := 0
+	for _, arch := range splitList(*archFlag) {
+		for _, sel := range selectForGen(arch, fs.Args(), splitList(*typesFlag)) {
+			path := filepath.Join(sel.pkg.Dir, gen.AsmHeaderFile(arch))
+			if err := os.WriteFile(path, gen.AsmHeader(arch, sel.structs), 0644); err != nil {
+				fmt.Fprintf(os.Stderr, "error writing header: %v\n", err)
+				os.Exit(exitError)
+			}
+			fmt.Printf("wrote %s (%d structs)\n", path, len(sel.structs))
+			generated++
+		}
+	}
+
+	if generated == 0 {
+		fmt.Fprintf(os.Stderr, "no struct selected: mark them with //viztruct:stable or use --types\n")
+		os.Exit(exitError)
+	}
+}
+
+func checkAsm(archs []string, patterns []string, types []string) {
+	var mismatches []gen.AsmMismatch
+	for _, arch := range archs {
+		for _, sel := range loadForGen(arch, patterns) {
+			for _, name := range sel.pkg.SFiles {
+				found, err := gen.CheckAsm(filepath.Join(sel.pkg.Dir, name), arch, sel.structs)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "%v\n", err)
+					os.Exit(exitError)
+				}
+				mismatches = append(mismatches, found...)
+			}
+
+			header := filepath.Join(sel.pkg.Dir, gen.AsmHeaderFile(arch))
+			current, err := os.ReadFile(header)
+			if err != nil {
+				continue // no header generated for this package
+			}
+			if !bytes.Equal(current, gen.AsmHeader(arch, lock.Select(sel.structs, types))) {
+				mismatches = append(mismatches, gen.AsmMismatch{File: header, Line: 1, Message: "header is out of date, run viztruct asmhdr"})
+			}
+		}
+	}
+
+	for _, m := range mismatches {
+		fmt.Printf("%s:%d: %s\n", m.File, m.Line, m.Message)
+	}
+	if len(mismatches) > 0 {
+		os.Exit(exitFindings)
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/buarki/viztruct/heapprof"
+	"github.com/buarki/viztruct/structi"
+)
+
+func rankByHeapProfile(structs []structi.Info, profilePath string, format OutputFormat) {
+	file, err := os.Open(profilePath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error opening heap profile: %v\n", err)
+		os.Exit(exitError)
+	}
+	defer file.Close()
+
+	profile, err := heapprof.Parse(file)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error reading heap profile: %v\n", err)
+		os.Exit(exitError)
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error getting working directory: %v\n", err)
+		os.Exit(exitError)
+	}
+
+	ranking, err := heapprof.Rank(structs, profile, heapprof.NewSourceResolver(wd))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error ranking structs: %v\n", err)
+		os.Exit(exitError)
+	}
+
+	if format == FormatJSON {
+		jsonOutput, err := json.MarshalIndent(ranking, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error encoding json: %v\n", err)
+			os.Exit(exitError)
+		}
+		fmt.Println(string(jsonOutput))
+		return
+	}
+
+	if len(ranking) == 0 {
+		fmt.Println("No allocation of the given structs found in the heap profile.")
+		return
+	}
+
+	fmt.Println("Structs ranked by bytes saved with the optimized layout:")
+	for _, w := range ranking {
+		fmt.Printf("\nStruct: %s\n", w.Name)
+		fmt.Printf("Size: %d bytes (optimized %d bytes)\n", w.OriginalSize, w.OptimizedSize)
+		fmt.Printf("Allocated: %d values, %d bytes\n", w.Objects, w.AllocBytes)
+		fmt.Printf("Wasted Space: %d bytes\n", w.WastedBytes)
+		fmt.Printf("Saved by Optimized Layout: %d bytes\n", w.SavedBytes)
+		fmt.Println("Allocation sites:")
+		for _, site := range w.Sites {
+			fmt.Printf("  %s\n", site)
+		}
+	}
+}
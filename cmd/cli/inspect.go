@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/buarki/viztruct/inspect"
+	"github.com/buarki/viztruct/structi"
+)
+
+func inspectMemory(structs []structi.Info, opts options) {
+	info, err := selectStruct(structs, opts.typeName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(exitError)
+	}
+
+	order, err := inspect.ParseByteOrder(opts.endian)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(exitError)
+	}
+
+	mem, err := inspect.ReadDump(opts.dump)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(exitError)
+	}
+	if opts.dumpOffset < 0 || opts.dumpOffset > int64(len(mem)) {
+		fmt.Fprintf(os.Stderr, "offset %d is outside the dump of %d bytes\n", opts.dumpOffset, len(mem))
+		os.Exit(exitError)
+	}
+
+	values, err := inspect.Decode(info, mem[opts.dumpOffset:], order)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(exitError)
+	}
+
+	if opts.format == FormatJSON {
+		jsonOutput, err := json.MarshalIndent(values, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error encoding json: %v\n", err)
+			os.Exit(exitError)
+		}
+		fmt.Println(string(jsonOutput))
+		return
+	}
+
+	fmt.Printf("\nStruct: %s (%d bytes, %s endian, at offset %d of the dump)\n\n", info.Name, info.OriginalSize, opts.endian, opts.dumpOffset)
+	for _, v := range values {
+		if v.IsPadding {
+			marker := ""
+			if v.NonZeroPadding {
+				marker = "  <-- non-zero padding"
+			}
+			fmt.Printf("  0x%04x  [%s] %d bytes: %s%s\n", v.Offset, v.Name, v.Size, v.Hex, marker)
+			continue
+		}
+		fmt.Printf("  0x%04x  %s (%s) = %s  [%s]\n", v.Offset, v.Name, v.TypeName, v.Value, v.Hex)
+	}
+}
+
+// selectStruct picks the struct to work on, the name is only required
+// when the input declares more than one.
+func selectStruct(structs []structi.Info, name string) (structi.Info, error) {
+	if name == "" {
+		if len(structs) != 1 {
+			return structi.Info{}, fmt.Errorf("input declares %d structs, pick one with --type", len(structs))
+		}
+		return structs[0], nil
+	}
+
+	for _, s := range structs {
+		if s.Name == name {
+			return s, nil
+		}
+	}
+	return structi.Info{}, fmt.Errorf("struct %s not found", name)
+}
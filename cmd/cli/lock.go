@@ -0,0 +1,105 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+
+	"github.com/buarki/viztruct/lock"
+	"github.com/buarki/viztruct/structi"
+)
+
+const defaultLockFile = "viztruct.lock"
+
+func runLock(args []string) {
+	fs := flag.NewFlagSet("lock", flag.ExitOnError)
+	lockPath := fs.String("lock", defaultLockFile, "Path of the lock file to write")
+	archFlag := fs.String("arch", runtime.GOARCH, "Comma separated architectures to record the layouts for")
+	typesFlag := fs.String("types", "", "Comma separated structs to lock besides the ones marked //viztruct:stable")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s lock [options] [packages]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Records the layout of the selected structs into a lock file.\n\nOptions:\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	byArch := analyzeByArch(splitList(*archFlag), fs.Args())
+
+	for arch, structs := range byArch {
+		byArch[arch] = lock.Select(structs, splitList(*typesFlag))
+	}
+
+	// a struct may only be built on some of the architectures
+	lockFile := lock.Record(byArch)
+	if len(lockFile.Structs) == 0 {
+		fmt.Fprintf(os.Stderr, "no struct to lock: mark them with //viztruct:stable or use --types\n")
+		os.Exit(exitError)
+	}
+
+	if err := lockFile.Write(*lockPath); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(exitError)
+	}
+	fmt.Printf("locked %d structs on %s into %s\n", len(lockFile.Structs), *archFlag, *lockPath)
+}
+
+func runCheck(args []string) {
+	fs := flag.NewFlagSet("check", flag.ExitOnError)
+	lockPath := fs.String("lock", defaultLockFile, "Path of the lock file to check against")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s check [options] [packages]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Fails if a locked struct layout changed on any architecture of the lock file.\n\nOptions:\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	lockFile, err := lock.Read(*lockPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(exitError)
+	}
+
+	mismatches := lock.Check(lockFile, analyzeByArch(lockFile.Archs, fs.Args()))
+	if len(mismatches) == 0 {
+		fmt.Printf("%d locked structs unchanged on %s\n", len(lockFile.Structs), strings.Join(lockFile.Archs, ", "))
+		return
+	}
+
+	for _, m := range mismatches {
+		fmt.Printf("%s (%s): %s\n", m.Struct, m.Arch, m.Message)
+	}
+	os.Exit(exitFindings)
+}
+
+// analyzeByArch analyses the packages once per architecture, as both the
+// files built and the sizes of types depend on it.
+func analyzeByArch(archs []string, patterns []string) map[string][]structi.Info {
+	byArch := make(map[string][]structi.Info)
+	for _, arch := range archs {
+		pkgs, err := structi.LoadPackages(structi.LoadConfig{GOARCH: arch}, patterns...)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error loading packages for %s: %v\n", arch, err)
+			os.Exit(exitError)
+		}
+
+		structs, err := structi.AnalysePackages(pkgs)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(exitError)
+		}
+		byArch[arch] = structs
+	}
+	return byArch
+}
+
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
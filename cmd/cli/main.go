@@ -6,8 +6,13 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
 	"strings"
 
+	"github.com/buarki/viztruct/grid"
+	"github.com/buarki/viztruct/lint"
 	"github.com/buarki/viztruct/structi"
 	"github.com/buarki/viztruct/svg"
 )
@@ -21,11 +26,51 @@ type OutputFormat string
 const (
 	FormatText OutputFormat = "txt"
 	FormatJSON OutputFormat = "json"
+	// FormatMarkdown renders tables meant to be posted as pull request comments.
+	FormatMarkdown OutputFormat = "markdown"
+	// FormatSARIF reports the lint findings for code scanning services.
+	FormatSARIF OutputFormat = "sarif"
 
 	svgFile = "struct-layout.svg"
+
+	// findings are told apart from failures of the tool so CI can gate
+	// merges on them
+	exitFindings = 1
+	exitError    = 2
 )
 
-func analyzeStructs(input string, format OutputFormat, generateSVG bool) {
+type options struct {
+	format         OutputFormat
+	generateSVG    bool
+	svg            svg.Options
+	svgSplit       bool
+	grid           string
+	heapProfile    string
+	escapeAnalysis bool
+	abi            bool
+	copies         bool
+	copyThreshold  int64
+	dump           string
+	dumpOffset     int64
+	endian         string
+	typeName       string
+	lint           lint.Config
+	baseline       *lint.Baseline
+	writeBaseline  string
+	archs          []string
+}
+
+// packageReport is the JSON output of package mode when analyses beyond the
+// struct layouts are requested.
+type packageReport struct {
+	Structs       []structi.Info         `json:"structs"`
+	SpilledParams []structi.SpilledParam `json:"spilled_params,omitempty"`
+	Copies        []structi.CopySite     `json:"copies,omitempty"`
+}
+
+// analyzeStructs analyses inline definitions, file is the path they were
+// read from if any.
+func analyzeStructs(input string, file string, opts options) {
 	structs, err := structi.AnalyseStructs(input)
 	if err != nil {
 		if errI, ok := err.(*structi.Error); ok {
@@ -33,57 +78,361 @@ func analyzeStructs(input string, format OutputFormat, generateSVG bool) {
 		} else {
 			fmt.Fprintf(os.Stderr, "%v\n", err)
 		}
-		os.Exit(1)
+		os.Exit(exitError)
 	}
 
-	if generateSVG {
-		svgOutput, err := svg.BuildVisualization(structs)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "error building SVG: %v\n", err)
-			os.Exit(1)
+	if file != "" {
+		if abs, err := filepath.Abs(file); err == nil {
+			file = abs
 		}
-		err = os.WriteFile(svgFile, []byte(svgOutput), 0644)
+		for i := range structs {
+			structs[i].File = file
+		}
+	}
+
+	report(structs, nil, opts)
+	gate(structs, nil, opts)
+}
+
+func analyzeBinary(path string, filter string, opts options) {
+	var include func(string) bool
+	if filter != "" {
+		re, err := regexp.Compile(filter)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "error writing svg file: %v\n", err)
-			os.Exit(1)
+			fmt.Fprintf(os.Stderr, "invalid filter: %v\n", err)
+			os.Exit(exitError)
+		}
+		include = re.MatchString
+	}
+
+	structs, err := structi.AnalyseBinary(path, include)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(exitError)
+	}
+
+	report(structs, nil, opts)
+	gate(structs, nil, opts)
+}
+
+func analyzePackages(patterns []string, opts options) {
+	pkgs, err := structi.LoadPackages(structi.LoadConfig{}, patterns...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error loading packages: %v\n", err)
+		os.Exit(exitError)
+	}
+
+	structs, err := structi.AnalysePackages(pkgs)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(exitError)
+	}
+
+	if opts.escapeAnalysis {
+		if err := structi.AnnotateEscapes(pkgs, structs); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(exitError)
+		}
+	}
+
+	// project-wide reports show the structs instantiated in bulk first
+	structi.SortByWeightedWaste(structs)
+
+	report(structs, pkgs, opts)
+
+	// maxsize directives must hold on every configured architecture
+	var maxSize []lint.Finding
+	for arch, archStructs := range analyzeByArch(otherArchs(opts.archs, pkgs), patterns) {
+		maxSize = append(maxSize, lint.CheckMaxSize(archStructs, arch)...)
+	}
+	gate(structs, maxSize, opts)
+}
+
+// otherArchs leaves out of archs the one the packages were loaded for.
+func otherArchs(archs []string, pkgs []*structi.Package) []string {
+	var others []string
+	for _, arch := range archs {
+		if len(pkgs) == 0 || arch != pkgs[0].GOARCH {
+			others = append(others, arch)
 		}
 	}
+	return others
+}
+
+func report(structs []structi.Info, pkgs []*structi.Package, opts options) {
+	var gridBytes int64
+	if opts.grid != "" {
+		arch := ""
+		if len(pkgs) > 0 {
+			arch = pkgs[0].GOARCH
+		}
+		// validated when parsing the flags
+		gridBytes, _ = grid.ParseRowBytes(opts.grid, structi.WordSize(arch))
+		opts.svg.Grid = gridBytes
+	}
+
+	if opts.generateSVG {
+		writeSVG(structs, opts)
+	}
+
+	if opts.heapProfile != "" {
+		rankByHeapProfile(structs, opts.heapProfile, opts.format)
+		return
+	}
+
+	if opts.dump != "" {
+		inspectMemory(structs, opts)
+		return
+	}
+
+	var spilled []structi.SpilledParam
+	if opts.abi {
+		for i := range structs {
+			// structs read from binaries or live values have no go/types
+			// struct to assign
+			if structs[i].Type == nil {
+				continue
+			}
+			for _, arch := range structi.ABIArchs {
+				assignment, err := structs[i].AssignRegisters(arch)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "%v\n", err)
+					os.Exit(exitError)
+				}
+				structs[i].Registers = append(structs[i].Registers, assignment)
+			}
+		}
+		spilled = structi.FindSpilledParams(pkgs)
+	}
+
+	var copies []structi.CopySite
+	if opts.copies {
+		copies = structi.FindCopies(pkgs, opts.copyThreshold)
+	}
 
-	if format == FormatJSON {
-		jsonOutput, err := json.MarshalIndent(structs, "", "  ")
+	if opts.format == FormatJSON {
+		var output any = structs
+		if pkgs != nil && (opts.abi || opts.copies) {
+			output = packageReport{Structs: structs, SpilledParams: spilled, Copies: copies}
+		}
+		jsonOutput, err := json.MarshalIndent(output, "", "  ")
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "error encoding json: %v\n", err)
-			os.Exit(1)
+			os.Exit(exitError)
 		}
 		fmt.Println(string(jsonOutput))
+	} else if opts.format == FormatSARIF {
+		printSARIF(findings(structs, lint.Check(structs, opts.lint), opts))
+	} else if opts.format == FormatMarkdown {
+		fmt.Print(markdownReport(packageReport{Structs: structs, SpilledParams: spilled, Copies: copies}, opts))
 	} else {
 		for _, s := range structs {
 			fmt.Printf("\nStruct: %s\n", s.Name)
+			if s.File != "" {
+				fmt.Printf("Package: %s (%s:%d)\n", s.Package, s.File, s.Line)
+			} else if s.Package != "" {
+				fmt.Printf("Package: %s\n", s.Package)
+			}
 			fmt.Printf("Original Size: %d bytes\n", s.OriginalSize)
 			fmt.Printf("Optimized Size: %d bytes\n", s.OptimizedSize)
 			fmt.Printf("Wasted Space: %d bytes (%.2f%%)\n", s.WastedBytes, s.WastedPercent)
+			if len(s.AllocSites) > 0 {
+				fmt.Printf("Allocation Sites: %s\n", formatAllocCounts(s.AllocCounts()))
+				fmt.Printf("Weighted Waste: %d\n", s.WeightedWaste())
+			}
+			if s.Escape != "" {
+				fmt.Printf("Allocated On: %s\n", s.Escape)
+			}
+			if reason, ignored := lint.Ignored(s); ignored {
+				if reason == "" {
+					reason = "no reason given"
+				}
+				fmt.Printf("Ignored: %s\n", reason)
+			}
 
 			fmt.Println("\nOriginal Layout:")
-			for _, f := range s.Fields {
-				if f.IsPadding {
-					fmt.Printf("  [padding] %d bytes at offset %d\n", f.Size, f.Offset)
-				} else {
-					fmt.Printf("  %s (%s) %d bytes at offset %d\n", f.Name, f.TypeName, f.Size, f.Offset)
+			fmt.Print(structi.FormatLayout(s.Fields))
+
+			fmt.Println("\nOptimized Layout:")
+			fmt.Print(structi.FormatLayout(s.OptimizedFields))
+
+			if gridBytes > 0 {
+				fmt.Printf("\nMemory Grid (%d bytes per row):\n", gridBytes)
+				fmt.Print(grid.Format(s.Fields, gridBytes))
+				fmt.Println("\nOptimized Memory Grid:")
+				fmt.Print(grid.Format(s.OptimizedFields, gridBytes))
+			}
+
+			if len(s.Registers) > 0 {
+				fmt.Println("\nRegister Assignment (passed by value):")
+				for _, r := range s.Registers {
+					if r.InRegisters {
+						fmt.Printf("  %s: %s\n", r.Arch, strings.Join(append(r.Ints, r.Floats...), " "))
+					} else {
+						fmt.Printf("  %s: stack (%s)\n", r.Arch, r.Reason)
+					}
 				}
 			}
+		}
 
-			fmt.Println("\nOptimized Layout:")
-			for _, f := range s.OptimizedFields {
-				if f.IsPadding {
-					fmt.Printf("  [padding] %d bytes at offset %d\n", f.Size, f.Offset)
-				} else {
-					fmt.Printf("  %s (%s) %d bytes at offset %d\n", f.Name, f.TypeName, f.Size, f.Offset)
+		if len(spilled) > 0 {
+			fmt.Println("\nStructs passed by value on the stack:")
+			for _, p := range spilled {
+				what := "parameter"
+				if p.Result {
+					what = "result"
+				}
+				fmt.Printf("  %s:%d: %s %s %s (%s, %d bytes) on %s: %s\n",
+					p.File, p.Line, p.Func, what, p.Param, p.Type, p.Size, strings.Join(p.Archs, ", "), p.Reason)
+			}
+		}
+
+		if opts.copies {
+			fmt.Printf("\nStructs over %d bytes copied by value:\n", opts.copyThreshold)
+			if len(copies) == 0 {
+				fmt.Println("  none")
+			}
+			for _, c := range copies {
+				name := ""
+				if c.Name != "" {
+					name = " " + c.Name
 				}
+				fmt.Printf("  %s:%d: %s%s copies %s (%d bytes)\n", c.File, c.Line, c.Kind, name, c.Type, c.Bytes)
 			}
 		}
 	}
 }
 
+// writeSVG draws structs into svgFile, or into one file per struct named
+// after its anchor with --svg-split.
+func writeSVG(structs []structi.Info, opts options) {
+	files := map[string]string{}
+	if opts.svgSplit {
+		documents, err := svg.BuildDocuments(structs, opts.svg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error building SVG: %v\n", err)
+			os.Exit(exitError)
+		}
+		for i, id := range svg.AnchorIDs(structs) {
+			files[id+".svg"] = documents[i]
+		}
+	} else {
+		document, err := svg.BuildDocument(structs, opts.svg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error building SVG: %v\n", err)
+			os.Exit(exitError)
+		}
+		files[svgFile] = document
+	}
+
+	for name, document := range files {
+		if err := os.WriteFile(name, []byte(document), 0644); err != nil {
+			fmt.Fprintf(os.Stderr, "error writing svg file: %v\n", err)
+			os.Exit(exitError)
+		}
+	}
+}
+
+// gate exits with exitFindings when structs exceed the configured
+// thresholds or their maxsize directive, extra holds the findings of
+// other architectures. The reasons go to stderr to keep the report
+// parseable.
+func gate(structs []structi.Info, extra []lint.Finding, opts options) {
+	if opts.writeBaseline != "" {
+		// maxsize directives are never baselined, a struct over its limit
+		// must not be accepted by the new baseline
+		failGate(append(lint.CheckMaxSize(structs, ""), extra...))
+
+		if err := lint.NewBaseline(structs).Write(opts.writeBaseline); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(exitError)
+		}
+		return
+	}
+
+	failGate(append(findings(structs, lint.Gate(structs, opts.lint), opts), extra...))
+}
+
+// failGate exits with exitFindings when there are failed findings.
+func failGate(failed []lint.Finding) {
+	if len(failed) == 0 {
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "\n%d findings fail the check:\n", len(failed))
+	for _, f := range failed {
+		if f.File != "" {
+			fmt.Fprintf(os.Stderr, "  %s:%d: %s\n", f.File, f.Line, f.Message)
+		} else {
+			fmt.Fprintf(os.Stderr, "  %s\n", f.Message)
+		}
+	}
+	os.Exit(exitFindings)
+}
+
+// readSVGStyle returns the theme selected by the --svg-theme, --svg-palette
+// and --svg-css flags and the style sheet to append to it, exiting on
+// invalid values.
+func readSVGStyle(themeName, palette, cssFile string) (svg.Theme, string) {
+	theme, err := svg.ThemeByName(themeName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(exitError)
+	}
+	if palette != "" {
+		if theme, err = svg.ReadPalette(palette, theme.Name); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(exitError)
+		}
+	}
+
+	var css []byte
+	if cssFile != "" {
+		if css, err = os.ReadFile(cssFile); err != nil {
+			fmt.Fprintf(os.Stderr, "error reading css file: %v\n", err)
+			os.Exit(exitError)
+		}
+	}
+	return theme, string(css)
+}
+
+// findings leaves out the findings accepted by the baseline, if any.
+func findings(structs []structi.Info, all []lint.Finding, opts options) []lint.Finding {
+	if opts.baseline == nil {
+		return all
+	}
+	return opts.baseline.Filter(structs, all, opts.lint)
+}
+
+func printSARIF(findings []lint.Finding) {
+	root, err := os.Getwd()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(exitError)
+	}
+
+	sarif, err := lint.SARIF(findings, binVersion, root)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(exitError)
+	}
+	fmt.Println(string(sarif))
+}
+
+func formatAllocCounts(counts map[structi.AllocKind]int) string {
+	kinds := make([]string, 0, len(counts))
+	for kind := range counts {
+		kinds = append(kinds, string(kind))
+	}
+	sort.Strings(kinds)
+
+	parts := make([]string, 0, len(kinds))
+	for _, kind := range kinds {
+		parts = append(parts, fmt.Sprintf("%s=%d", kind, counts[structi.AllocKind(kind)]))
+	}
+	return strings.Join(parts, " ")
+}
+
 func readStructFromFile(filePath string) (string, error) {
 	file, err := os.Open(filePath)
 	if err != nil {
@@ -106,12 +455,48 @@ func readStructFromFile(filePath string) (string, error) {
 }
 
 func printUsage() {
-	fmt.Fprintf(os.Stderr, "Usage: %s [options]\n\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "Usage: %s [options] [packages]\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "       %s lock [--lock file] [--arch list] [--types list] [packages]\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "       %s check [--lock file] [packages]\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "       %s diff [--format txt|json|markdown] [--svg] [--svg-width n] [--svg-theme name] <rev1> <rev2> [packages]\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "       %s asserts [--arch list] [--types list] [packages]\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "       %s asmhdr [--arch list] [--types list] [--check] [packages]\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "       %s cheader [--arch list] [--types list] [--compare file.h] [packages]\n\n", os.Args[0])
 	fmt.Fprintf(os.Stderr, "Options:\n")
-	fmt.Fprintf(os.Stderr, "  --format string    Output format (json or txt) (default \"txt\")\n")
+	fmt.Fprintf(os.Stderr, "  --format string    Output format (txt, json, markdown or sarif) (default \"txt\")\n")
 	fmt.Fprintf(os.Stderr, "  --struct string    Inline struct definition\n")
 	fmt.Fprintf(os.Stderr, "  --file string      Path to file containing struct definitions\n")
+	fmt.Fprintf(os.Stderr, "  --binary string    Path to an ELF binary or object file with DWARF debug info\n")
+	fmt.Fprintf(os.Stderr, "  --filter string    Regexp selecting the qualified struct names read from --binary\n")
 	fmt.Fprintf(os.Stderr, "  --svg              Generate SVG visualization (default false)\n")
+	fmt.Fprintf(os.Stderr, "  --svg-toc          List the structs at the top of the SVG, linking to their layouts (default false)\n")
+	fmt.Fprintf(os.Stderr, "  --svg-width float  Width of the SVG in pixels, its height follows the content (default 1200)\n")
+	fmt.Fprintf(os.Stderr, "  --svg-scale string Width of the SVG blocks: linear, min-width, log or wrap (default \"linear\")\n")
+	fmt.Fprintf(os.Stderr, "  --svg-min-block-width float  Narrowest block in pixels with --svg-scale min-width (default 12)\n")
+	fmt.Fprintf(os.Stderr, "  --svg-row-bytes int  Bytes per row with --svg-scale wrap (default 64)\n")
+	fmt.Fprintf(os.Stderr, "  --grid string      Show the layouts as a memory grid with rows of a word, a cacheline or N bytes\n")
+	fmt.Fprintf(os.Stderr, "  --svg-theme string SVG theme: %s (default \"light\")\n", strings.Join(svg.ThemeNames(), ", "))
+	fmt.Fprintf(os.Stderr, "  --svg-palette string  JSON palette file overriding the colors of the theme\n")
+	fmt.Fprintf(os.Stderr, "  --svg-css string   CSS file appended to the SVG style sheet\n")
+	fmt.Fprintf(os.Stderr, "  --svg-split        Write one struct-<name>.svg file per struct (default false)\n")
+	fmt.Fprintf(os.Stderr, "  --pprof string     Heap profile used to rank structs by bytes saved in production\n")
+	fmt.Fprintf(os.Stderr, "  --escape           Run the compiler escape analysis on packages (default false)\n")
+	fmt.Fprintf(os.Stderr, "  --abi              Show register assignment and structs passed on the stack (default false)\n")
+	fmt.Fprintf(os.Stderr, "  --copies           Report large structs copied by value in packages (default false)\n")
+	fmt.Fprintf(os.Stderr, "  --copy-threshold int  Size in bytes above which a copy is reported (default 128)\n")
+	fmt.Fprintf(os.Stderr, "  --dump string      Memory dump (file with raw bytes, hex or xxd output, or a hex string) to decode\n")
+	fmt.Fprintf(os.Stderr, "  --dump-offset int  Offset of the struct in the dump (default 0)\n")
+	fmt.Fprintf(os.Stderr, "  --endian string    Byte order of the dump (little or big) (default \"little\")\n")
+	fmt.Fprintf(os.Stderr, "  --type string      Struct to use when the input declares several\n")
+	fmt.Fprintf(os.Stderr, "  --warning-percent float  Wasted percent from which sarif padding findings are warnings (default 10)\n")
+	fmt.Fprintf(os.Stderr, "  --error-percent float    Wasted percent from which sarif padding findings are errors (default 25)\n")
+	fmt.Fprintf(os.Stderr, "  --max-wasted-bytes int      Exit with code 1 when a struct wastes more bytes (default -1, disabled)\n")
+	fmt.Fprintf(os.Stderr, "  --max-wasted-percent float  Exit with code 1 when a struct wastes a larger percent (default -1, disabled)\n")
+	fmt.Fprintf(os.Stderr, "  --min-savings int           Ignore structs saving fewer bytes once reordered, 0 gates on all waste (default 1)\n")
+	fmt.Fprintf(os.Stderr, "  --require-size-class        Only fail on structs whose reordering changes their size class (default false)\n")
+	fmt.Fprintf(os.Stderr, "  --arch string               Comma separated architectures maxsize directives are checked on in packages\n")
+	fmt.Fprintf(os.Stderr, "  --baseline string           Only report findings new or worse than in this baseline\n")
+	fmt.Fprintf(os.Stderr, "  --write-baseline string     Write the current structs as the baseline to this file\n")
 	fmt.Fprintf(os.Stderr, "  --version          Show version information\n")
 	fmt.Fprintf(os.Stderr, "  --help             Show help message\n")
 	fmt.Fprintf(os.Stderr, "\nExamples:\n")
@@ -119,15 +504,80 @@ func printUsage() {
 	fmt.Fprintf(os.Stderr, "  %s --file structs.go\n", os.Args[0])
 	fmt.Fprintf(os.Stderr, "  %s --format json --struct 'type MyStruct struct { a int; b string }'\n", os.Args[0])
 	fmt.Fprintf(os.Stderr, "  %s --svg --struct 'type MyStruct struct { a int; b string }'\n", os.Args[0])
-	os.Exit(1)
+	fmt.Fprintf(os.Stderr, "  %s --file structs.go --pprof heap.pb.gz\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s --binary ./server --filter '^main\\.'\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s --file structs.go --type Header --dump segment.xxd --endian big\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s ./...\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s --escape ./...\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s --copies --copy-threshold 256 ./...\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s --format sarif ./... > viztruct.sarif\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s --max-wasted-percent 20 --min-savings 8 ./...\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s --write-baseline viztruct-baseline.json ./...\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s --baseline viztruct-baseline.json --max-wasted-bytes 0 ./...\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s --arch amd64,arm64,386 ./...\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "\nExit codes: 0 no findings, 1 structs exceed the thresholds or their maxsize, 2 error\n")
+	os.Exit(exitError)
 }
 
 func main() {
-	formatFlag := flag.String("format", "txt", "Output format (json or txt)")
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "lock":
+			runLock(os.Args[2:])
+			return
+		case "check":
+			runCheck(os.Args[2:])
+			return
+		case "diff":
+			runDiff(os.Args[2:])
+			return
+		case "asserts":
+			runAsserts(os.Args[2:])
+			return
+		case "asmhdr":
+			runAsmHeader(os.Args[2:])
+			return
+		case "cheader":
+			runCHeader(os.Args[2:])
+			return
+		}
+	}
+
+	formatFlag := flag.String("format", "txt", "Output format (txt, json, markdown or sarif)")
 	structDef := flag.String("struct", "", "Struct definition to visualize")
 	fileFlag := flag.String("file", "", "Path to file containing struct definitions")
+	binaryFlag := flag.String("binary", "", "Path to an ELF binary or object file with DWARF debug info")
+	filterFlag := flag.String("filter", "", "Regexp selecting the qualified struct names read from --binary")
 	helpFlag := flag.Bool("help", false, "Show help message")
 	svgFlag := flag.Bool("svg", false, "Generate SVG visualization")
+	svgTOCFlag := flag.Bool("svg-toc", false, "List the structs at the top of the SVG, linking to their layouts")
+	svgWidthFlag := flag.Float64("svg-width", 1200, "Width of the SVG in pixels")
+	svgScaleFlag := flag.String("svg-scale", "linear", "Width of the SVG blocks: linear, min-width, log or wrap")
+	svgMinBlockWidth := flag.Float64("svg-min-block-width", 12, "Narrowest block in pixels with --svg-scale min-width")
+	svgRowBytes := flag.Int64("svg-row-bytes", 64, "Bytes per row with --svg-scale wrap")
+	gridFlag := flag.String("grid", "", "Show the layouts as a memory grid with rows of a word, a cacheline or N bytes, in the text and SVG output")
+	svgThemeFlag := flag.String("svg-theme", "light", "SVG theme: "+strings.Join(svg.ThemeNames(), ", "))
+	svgPaletteFlag := flag.String("svg-palette", "", "JSON palette file overriding the colors of the theme")
+	svgCSSFlag := flag.String("svg-css", "", "CSS file appended to the SVG style sheet")
+	svgSplitFlag := flag.Bool("svg-split", false, "Write one SVG file per struct instead of "+svgFile)
+	pprofFlag := flag.String("pprof", "", "Heap profile used to rank structs by bytes saved in production")
+	escapeFlag := flag.Bool("escape", false, "Run the compiler escape analysis on packages")
+	abiFlag := flag.Bool("abi", false, "Show register assignment and structs passed on the stack")
+	copiesFlag := flag.Bool("copies", false, "Report large structs copied by value in packages")
+	copyThreshold := flag.Int64("copy-threshold", 128, "Size in bytes above which a copy is reported")
+	dumpFlag := flag.String("dump", "", "Memory dump (file with raw bytes, hex or xxd output, or a hex string) to decode")
+	dumpOffset := flag.Int64("dump-offset", 0, "Offset of the struct in the dump")
+	endianFlag := flag.String("endian", "little", "Byte order of the dump (little or big)")
+	typeFlag := flag.String("type", "", "Struct to use when the input declares several")
+	warningPercent := flag.Float64("warning-percent", lint.DefaultConfig().WarningPercent, "Wasted percent from which sarif padding findings are warnings")
+	errorPercent := flag.Float64("error-percent", lint.DefaultConfig().ErrorPercent, "Wasted percent from which sarif padding findings are errors")
+	maxWastedBytes := flag.Int64("max-wasted-bytes", lint.DefaultConfig().MaxWastedBytes, "Exit with code 1 when a struct wastes more bytes, negative disables")
+	maxWastedPercent := flag.Float64("max-wasted-percent", lint.DefaultConfig().MaxWastedPercent, "Exit with code 1 when a struct wastes a larger percent, negative disables")
+	minSavings := flag.Int64("min-savings", lint.DefaultConfig().MinSavings, "Ignore structs saving fewer bytes once reordered, 0 gates on all waste")
+	requireSizeClass := flag.Bool("require-size-class", false, "Only fail on structs whose reordering changes their size class")
+	archFlag := flag.String("arch", "", "Comma separated architectures maxsize directives are checked on in packages")
+	baselineFlag := flag.String("baseline", "", "Only report findings new or worse than in this baseline")
+	writeBaselineFlag := flag.String("write-baseline", "", "Write the current structs as the baseline to this file")
 	version := flag.Bool("version", false, "Show version information")
 
 	flag.Parse()
@@ -142,22 +592,85 @@ func main() {
 	}
 
 	format := OutputFormat(*formatFlag)
-	if format != FormatJSON && format != FormatText {
-		fmt.Fprintf(os.Stderr, "invalid format: %s. use 'json' or 'txt'\n", format)
-		os.Exit(1)
+	if format != FormatJSON && format != FormatText && format != FormatMarkdown && format != FormatSARIF {
+		fmt.Fprintf(os.Stderr, "invalid format: %s. use 'txt', 'json', 'markdown' or 'sarif'\n", format)
+		os.Exit(exitError)
+	}
+
+	svgScale, err := svg.ParseScale(*svgScaleFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(exitError)
+	}
+
+	svgTheme, svgCSS := readSVGStyle(*svgThemeFlag, *svgPaletteFlag, *svgCSSFlag)
+
+	if *gridFlag != "" {
+		if _, err := grid.ParseRowBytes(*gridFlag, 8); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(exitError)
+		}
+	}
+
+	opts := options{
+		format:      format,
+		generateSVG: *svgFlag || *svgSplitFlag,
+		svg: svg.Options{
+			Width:         *svgWidthFlag,
+			TOC:           *svgTOCFlag,
+			Scale:         svgScale,
+			MinBlockWidth: *svgMinBlockWidth,
+			RowBytes:      *svgRowBytes,
+			Theme:         svgTheme,
+			CSS:           svgCSS,
+		},
+		svgSplit:       *svgSplitFlag,
+		grid:           *gridFlag,
+		heapProfile:    *pprofFlag,
+		escapeAnalysis: *escapeFlag,
+		abi:            *abiFlag,
+		copies:         *copiesFlag,
+		copyThreshold:  *copyThreshold,
+		dump:           *dumpFlag,
+		dumpOffset:     *dumpOffset,
+		endian:         *endianFlag,
+		typeName:       *typeFlag,
+		lint:           lint.DefaultConfig(),
+		writeBaseline:  *writeBaselineFlag,
+		archs:          splitList(*archFlag),
+	}
+	opts.lint.WarningPercent = *warningPercent
+	opts.lint.ErrorPercent = *errorPercent
+	opts.lint.MaxWastedBytes = *maxWastedBytes
+	opts.lint.MaxWastedPercent = *maxWastedPercent
+	opts.lint.MinSavings = *minSavings
+	opts.lint.RequireSizeClassCrossing = *requireSizeClass
+
+	if *baselineFlag != "" {
+		baseline, err := lint.ReadBaseline(*baselineFlag)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(exitError)
+		}
+		opts.baseline = baseline
 	}
 
 	var input string
-	var err error
 
 	if *fileFlag != "" {
 		input, err = readStructFromFile(*fileFlag)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "error reading struct from file: %v\n", err)
-			os.Exit(1)
+			os.Exit(exitError)
 		}
 	} else if *structDef != "" {
 		input = *structDef
+	} else if *binaryFlag != "" {
+		analyzeBinary(*binaryFlag, *filterFlag, opts)
+		return
+	} else if flag.NArg() > 0 {
+		analyzePackages(flag.Args(), opts)
+		return
 	} else {
 		fmt.Fprintf(os.Stderr, "error: no struct definition provided\n")
 		printUsage()
@@ -168,5 +681,5 @@ func main() {
 		printUsage()
 	}
 
-	analyzeStructs(input, format, *svgFlag)
+	analyzeStructs(input, *fileFlag, opts)
 }
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/buarki/viztruct/diff"
+	"github.com/buarki/viztruct/structi"
+)
+
+// markdownDiff renders the changes between two revisions as a pull request
+// comment: a summary table followed by the details of each struct folded
+// into a <details> block.
+func markdownDiff(changes []diff.Change, before, after string) string {
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "### Struct layout changes (`%s` → `%s`)\n\n", before, after)
+	if len(changes) == 0 {
+		sb.WriteString("No struct layout changed.\n")
+		return sb.String()
+	}
+
+	sb.WriteString("| Struct | Size | Waste | Size class |\n")
+	sb.WriteString("|---|---|---|---|\n")
+	for _, c := range changes {
+		fmt.Fprintf(&sb, "| `%s` | %s | %s | %s |\n", markdownEscape(c.Struct),
+			markdownDelta(c.Before, c.After, func(i *structi.Info) int64 { return i.OriginalSize }),
+			markdownDelta(c.Before, c.After, func(i *structi.Info) int64 { return i.WastedBytes }),
+			markdownDelta(c.Before, c.After, func(i *structi.Info) int64 { return structi.SizeClass(i.OriginalSize) }))
+	}
+
+	for _, c := range changes {
+		fmt.Fprintf(&sb, "\n<details>\n<summary><code>%s</code>: %s</summary>\n\n", c.Struct, c.Summary())
+
+		if len(c.Fields) > 0 {
+			for _, f := range c.Fields {
+				fmt.Fprintf(&sb, "- %s\n", f)
+			}
+			sb.WriteString("\n")
+		}
+
+		if c.After != nil {
+			changed := make(map[string]bool)
+			for _, f := range c.Fields {
+				changed[f.Name] = true
+			}
+			fmt.Fprintf(&sb, "Layout at `%s`:\n\n", after)
+			writeMarkdownFields(&sb, c.After.Fields, changed)
+			writeMarkdownSuggestion(&sb, *c.After)
+		}
+
+		sb.WriteString("\n</details>\n")
+	}
+
+	return sb.String()
+}
+
+// markdownReport renders the analysed structs in the same shape as
+// markdownDiff, comparing each struct with its optimized layout, followed
+// by the ABI and copy findings when --abi or --copies asked for them.
+func markdownReport(r packageReport, opts options) string {
+	var sb strings.Builder
+
+	sb.WriteString("### Struct layouts\n\n")
+	sb.WriteString("| Struct | Size | Optimized | Waste | Size class |\n")
+	sb.WriteString("|---|---|---|---|---|\n")
+	for _, s := range r.Structs {
+		fmt.Fprintf(&sb, "| `%s` | %d | %d | %d (%.2f%%) | %d → %d |\n", markdownEscape(s.QualifiedName()),
+			s.OriginalSize, s.OptimizedSize, s.WastedBytes, s.WastedPercent,
+			structi.SizeClass(s.OriginalSize), structi.SizeClass(s.OptimizedSize))
+	}
+
+	for _, s := range r.Structs {
+		fmt.Fprintf(&sb, "\n<details>\n<summary><code>%s</code>: %d bytes, %d wasted</summary>\n\n",
+			s.QualifiedName(), s.OriginalSize, s.WastedBytes)
+		writeMarkdownFields(&sb, s.Fields, nil)
+		writeMarkdownSuggestion(&sb, s)
+		writeMarkdownRegisters(&sb, s.Registers)
+		sb.WriteString("\n</details>\n")
+	}
+
+	if len(r.SpilledParams) > 0 {
+		sb.WriteString("\n#### Structs passed by value on the stack\n\n")
+		sb.WriteString("| Position | Function | Parameter | Type | Size | Architectures | Reason |\n")
+		sb.WriteString("|---|---|---|---|---|---|---|\n")
+		for _, p := range r.SpilledParams {
+			param := p.Param
+			if p.Result {
+				param += " (result)"
+			}
+			fmt.Fprintf(&sb, "| %s:%d | `%s` | %s | `%s` | %d | %s | %s |\n", p.File, p.Line,
+				markdownEscape(p.Func), markdownEscape(param), markdownEscape(p.Type), p.Size,
+				strings.Join(p.Archs, ", "), markdownEscape(p.Reason))
+		}
+	}
+
+	if opts.copies {
+		fmt.Fprintf(&sb, "\n#### Structs over %d bytes copied by value\n\n", opts.copyThreshold)
+		if len(r.Copies) == 0 {
+			sb.WriteString("None.\n")
+		} else {
+			sb.WriteString("| Position | Kind | Name | Type | Size |\n")
+			sb.WriteString("|---|---|---|---|---|\n")
+			for _, c := range r.Copies {
+				fmt.Fprintf(&sb, "| %s:%d | %s | %s | `%s` | %d |\n", c.File, c.Line, c.Kind,
+					markdownEscape(c.Name), markdownEscape(c.Type), c.Bytes)
+			}
+		}
+	}
+
+	return sb.String()
+}
+
+func writeMarkdownFields(sb *strings.Builder, fields []structi.Field, changed map[string]bool) {
+	sb.WriteString("| Field | Type | Offset | Size |\n")
+	sb.WriteString("|---|---|---|---|\n")
+	for _, f := range fields {
+		if f.IsPadding {
+			fmt.Fprintf(sb, "| _%s_ | | %d | %d |\n", f.Name, f.Offset, f.Size)
+			continue
+		}
+		name := markdownEscape(f.Name)
+		if changed[f.Name] {
+			name = "**" + name + "**"
+		}
+		fmt.Fprintf(sb, "| %s | `%s` | %d | %d |\n", name, markdownEscape(f.TypeName), f.Offset, f.Size)
+	}
+}
+
+func writeMarkdownSuggestion(sb *strings.Builder, s structi.Info) {
+	if s.OptimizedSize >= s.OriginalSize {
+		return
+	}
+
+	fmt.Fprintf(sb, "\nSuggested order (%d → %d bytes):\n\n```go\ntype %s struct {\n", s.OriginalSize, s.OptimizedSize, s.Name)
+	for _, f := range s.OptimizedFields {
+		if !f.IsPadding {
+			fmt.Fprintf(sb, "\t%s %s\n", f.Name, f.TypeName)
+		}
+	}
+	sb.WriteString("}\n```\n")
+}
+
+func writeMarkdownRegisters(sb *strings.Builder, registers []structi.RegisterAssignment) {
+	if len(registers) == 0 {
+		return
+	}
+
+	sb.WriteString("\nRegister assignment (passed by value):\n\n")
+	for _, r := range registers {
+		if r.InRegisters {
+			fmt.Fprintf(sb, "- %s: `%s`\n", r.Arch, strings.Join(append(r.Ints, r.Floats...), " "))
+		} else {
+			fmt.Fprintf(sb, "- %s: stack (%s)\n", r.Arch, r.Reason)
+		}
+	}
+}
+
+// markdownDelta formats a value of a struct at both revisions, like
+// "48 → 64 (+16)".
+func markdownDelta(before, after *structi.Info, value func(*structi.Info) int64) string {
+	switch {
+	case before == nil:
+		return fmt.Sprintf("added, %d", value(after))
+	case after == nil:
+		return fmt.Sprintf("removed, was %d", value(before))
+	}
+
+	b, a := value(before), value(after)
+	if a == b {
+		return fmt.Sprintf("%d", a)
+	}
+	return fmt.Sprintf("%d → %d (%+d)", b, a, a-b)
+}
+
+func markdownEscape(s string) string {
+	return strings.ReplaceAll(s, "|", `\|`)
+}
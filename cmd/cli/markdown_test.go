@@ -0,0 +1,57 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/buarki/viztruct/structi"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files")
+
+func TestMarkdownReport(t *testing.T) {
+	structs, err := structi.AnalyseStructs(`type Sample struct {
+		A bool
+		B int64
+		C bool
+	}`)
+	if err != nil {
+		t.Fatalf("analyse error: %v", err)
+	}
+	assignment, err := structs[0].AssignRegisters("amd64")
+	if err != nil {
+		t.Fatalf("assign error: %v", err)
+	}
+	structs[0].Registers = append(structs[0].Registers, assignment)
+
+	r := packageReport{
+		Structs: structs,
+		SpilledParams: []structi.SpilledParam{{
+			Func: "Handle", Param: "req", Type: "main.Request", Size: 256,
+			Archs: []string{"amd64", "arm64"}, Reason: "req.Body is an array of 32 elements",
+			File: "main.go", Line: 12,
+		}},
+		Copies: []structi.CopySite{{
+			Kind: structi.CopyRange, Type: "main.Request", Bytes: 256, Name: "r",
+			File: "main.go", Line: 20, Column: 2,
+		}},
+	}
+
+	got := markdownReport(r, options{copies: true, copyThreshold: 128})
+
+	golden := filepath.Join("testdata", "markdown_report.golden")
+	if *update {
+		if err := os.WriteFile(golden, []byte(got), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != string(want) {
+		t.Errorf("markdownReport() =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,185 @@
+package diff
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/buarki/viztruct/structi"
+)
+
+type Status string
+
+const (
+	Added   Status = "added"
+	Removed Status = "removed"
+	Changed Status = "changed"
+)
+
+// FieldChange is a field added, removed, moved or retyped between the two
+// layouts of a struct.
+type FieldChange struct {
+	Name         string `json:"name"`
+	Status       Status `json:"status"`
+	BeforeType   string `json:"before_type,omitempty"`
+	AfterType    string `json:"after_type,omitempty"`
+	BeforeOffset int64  `json:"before_offset"`
+	AfterOffset  int64  `json:"after_offset"`
+	BeforeSize   int64  `json:"before_size"`
+	AfterSize    int64  `json:"after_size"`
+}
+
+// Change is a struct whose layout differs between two revisions. Before is
+// nil for added structs and After for removed ones.
+type Change struct {
+	Struct     string        `json:"struct"`
+	Status     Status        `json:"status"`
+	Before     *structi.Info `json:"before,omitempty"`
+	After      *structi.Info `json:"after,omitempty"`
+	SizeDelta  int64         `json:"size_delta"`
+	WasteDelta int64         `json:"waste_delta"`
+	Fields     []FieldChange `json:"fields,omitempty"`
+}
+
+// Compare matches the structs of two revisions by qualified name and
+// returns the ones whose size, waste or field offsets changed, sorted by
+// name. Structs with an identical layout are left out.
+func Compare(before, after []structi.Info) []Change {
+	old := make(map[string]*structi.Info)
+	for i := range before {
+		old[before[i].QualifiedName()] = &before[i]
+	}
+
+	var changes []Change
+	seen := make(map[string]bool)
+	for i := range after {
+		a := &after[i]
+		name := a.QualifiedName()
+		seen[name] = true
+
+		b, ok := old[name]
+		if !ok {
+			changes = append(changes, Change{
+				Struct:     name,
+				Status:     Added,
+				After:      a,
+				SizeDelta:  a.OriginalSize,
+				WasteDelta: a.WastedBytes,
+			})
+			continue
+		}
+
+		c := Change{
+			Struct:     name,
+			Status:     Changed,
+			Before:     b,
+			After:      a,
+			SizeDelta:  a.OriginalSize - b.OriginalSize,
+			WasteDelta: a.WastedBytes - b.WastedBytes,
+			Fields:     compareFields(b.Fields, a.Fields),
+		}
+		if c.SizeDelta != 0 || c.WasteDelta != 0 || len(c.Fields) > 0 {
+			changes = append(changes, c)
+		}
+	}
+
+	for i := range before {
+		b := &before[i]
+		if name := b.QualifiedName(); !seen[name] {
+			changes = append(changes, Change{
+				Struct:     name,
+				Status:     Removed,
+				Before:     b,
+				SizeDelta:  -b.OriginalSize,
+				WasteDelta: -b.WastedBytes,
+			})
+		}
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].Struct < changes[j].Struct
+	})
+	return changes
+}
+
+func compareFields(before, after []structi.Field) []FieldChange {
+	old := make(map[string]structi.Field)
+	for _, f := range before {
+		if !f.IsPadding {
+			old[f.Name] = f
+		}
+	}
+
+	var changes []FieldChange
+	seen := make(map[string]bool)
+	for _, a := range after {
+		if a.IsPadding {
+			continue
+		}
+		seen[a.Name] = true
+
+		b, ok := old[a.Name]
+		switch {
+		case !ok:
+			changes = append(changes, FieldChange{
+				Name:        a.Name,
+				Status:      Added,
+				AfterType:   a.TypeName,
+				AfterOffset: a.Offset,
+				AfterSize:   a.Size,
+			})
+		case b.Offset != a.Offset || b.Size != a.Size || b.TypeName != a.TypeName:
+			changes = append(changes, FieldChange{
+				Name:         a.Name,
+				Status:       Changed,
+				BeforeType:   b.TypeName,
+				AfterType:    a.TypeName,
+				BeforeOffset: b.Offset,
+				AfterOffset:  a.Offset,
+				BeforeSize:   b.Size,
+				AfterSize:    a.Size,
+			})
+		}
+	}
+
+	for _, b := range before {
+		if !b.IsPadding && !seen[b.Name] {
+			changes = append(changes, FieldChange{
+				Name:         b.Name,
+				Status:       Removed,
+				BeforeType:   b.TypeName,
+				BeforeOffset: b.Offset,
+				BeforeSize:   b.Size,
+			})
+		}
+	}
+
+	return changes
+}
+
+// Summary describes the change of the struct as a whole, e.g.
+// "48 -> 64 bytes (+16), waste 4 -> 12 bytes (+8)".
+func (c Change) Summary() string {
+	switch c.Status {
+	case Added:
+		return fmt.Sprintf("added, %d bytes (%d wasted)", c.After.OriginalSize, c.After.WastedBytes)
+	case Removed:
+		return fmt.Sprintf("removed, was %d bytes (%d wasted)", c.Before.OriginalSize, c.Before.WastedBytes)
+	}
+	return fmt.Sprintf("%d -> %d bytes (%+d), waste %d -> %d bytes (%+d)",
+		c.Before.OriginalSize, c.After.OriginalSize, c.SizeDelta,
+		c.Before.WastedBytes, c.After.WastedBytes, c.WasteDelta)
+}
+
+func (f FieldChange) String() string {
+	switch f.Status {
+	case Added:
+		return fmt.Sprintf("field %s (%s) added at offset %d", f.Name, f.AfterType, f.AfterOffset)
+	case Removed:
+		return fmt.Sprintf("field %s (%s) removed from offset %d", f.Name, f.BeforeType, f.BeforeOffset)
+	}
+	if f.BeforeType != f.AfterType || f.BeforeSize != f.AfterSize {
+		return fmt.Sprintf("field %s changed from %s (%d bytes) at offset %d to %s (%d bytes) at offset %d",
+			f.Name, f.BeforeType, f.BeforeSize, f.BeforeOffset, f.AfterType, f.AfterSize, f.AfterOffset)
+	}
+	return fmt.Sprintf("field %s moved from offset %d to %d", f.Name, f.BeforeOffset, f.AfterOffset)
+}
@@ -0,0 +1,133 @@
+package diff
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/buarki/viztruct/structi"
+)
+
+func analyse(t *testing.T, input string) []structi.Info {
+	t.Helper()
+	infos, err := structi.AnalyseStructs(input)
+	if err != nil {
+		t.Fatalf("analyse error: %v", err)
+	}
+	return infos
+}
+
+func TestCompare(t *testing.T) {
+	before := analyse(t, `package main
+
+type Session struct {
+	ID    int64
+	Open  bool
+	Users []string
+	Gone  int
+}
+
+type Same struct {
+	A int
+}
+
+type Old struct {
+	A int
+}
+`)
+	after := analyse(t, `package main
+
+type Session struct {
+	ID    int64
+	Open  bool
+	Last  int64
+	Users []string
+}
+
+type Same struct {
+	A int
+}
+
+type New struct {
+	B bool
+}
+`)
+
+	changes := Compare(before, after)
+
+	var got []string
+	for _, c := range changes {
+		got = append(got, c.Struct+" "+string(c.Status))
+	}
+	if want := []string{"New added", "Old removed", "Session changed"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got changes %v, want %v", got, want)
+	}
+
+	session := changes[2]
+	if session.SizeDelta != 0 || session.Summary() != "48 -> 48 bytes (+0), waste 7 -> 7 bytes (+0)" {
+		t.Errorf("unexpected summary %q", session.Summary())
+	}
+
+	var fields []string
+	for _, f := range session.Fields {
+		fields = append(fields, f.String())
+	}
+	want := []string{
+		"field Last (int64) added at offset 16",
+		"field Users moved from offset 16 to 24",
+		"field Gone (int) removed from offset 40",
+	}
+	if !reflect.DeepEqual(fields, want) {
+		t.Errorf("got field changes\n%v\nwant\n%v", fields, want)
+	}
+}
+
+func TestWorktree(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+
+	repo := t.TempDir()
+	run := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = repo
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Join(repo, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(repo, "sub", "a.txt"), []byte("one"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	run("init", "-q")
+	run("add", "-A")
+	run("commit", "-q", "-m", "one")
+	if err := os.WriteFile(filepath.Join(repo, "sub", "a.txt"), []byte("two"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	run("commit", "-q", "-am", "two")
+
+	dir, cleanup, err := Worktree(filepath.Join(repo, "sub"), "HEAD~1")
+	if err != nil {
+		t.Fatalf("worktree error: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "a.txt"))
+	if err != nil {
+		t.Fatalf("read error: %v", err)
+	}
+	if string(data) != "one" {
+		t.Errorf("got %q from the worktree, want the first revision", data)
+	}
+
+	cleanup()
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("worktree %s not removed", dir)
+	}
+}
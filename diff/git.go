@@ -0,0 +1,52 @@
+package diff
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Worktree checks rev out into a temporary git worktree of the repository
+// holding dir. It returns the directory matching dir inside the worktree
+// and a function removing the worktree once done.
+func Worktree(dir, rev string) (string, func(), error) {
+	top, err := git(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", nil, err
+	}
+	prefix, err := git(dir, "rev-parse", "--show-prefix")
+	if err != nil {
+		return "", nil, err
+	}
+
+	tmp, err := os.MkdirTemp("", "viztruct-worktree-")
+	if err != nil {
+		return "", nil, fmt.Errorf("error creating worktree directory: %v", err)
+	}
+	if _, err := git(top, "worktree", "add", "--detach", tmp, rev); err != nil {
+		os.RemoveAll(tmp)
+		return "", nil, err
+	}
+
+	cleanup := func() {
+		git(top, "worktree", "remove", "--force", tmp)
+		os.RemoveAll(tmp)
+	}
+	return filepath.Join(tmp, prefix), cleanup, nil
+}
+
+func git(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git %s failed: %v\n%s", strings.Join(args, " "), err, stderr.String())
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
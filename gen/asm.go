@@ -0,0 +1,168 @@
+package gen
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/buarki/viztruct/structi"
+)
+
+// AsmHeaderFile is the name of the header holding the constants of arch.
+func AsmHeaderFile(arch string) string {
+	return fmt.Sprintf("viztruct_asm_%s.h", arch)
+}
+
+// AsmHeader generates go_asm.h style constants for the structs: T__size
+// for the size of T and T_field for the offset of each field.
+func AsmHeader(arch string, infos []structi.Info) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by \"viztruct asmhdr\"; DO NOT EDIT.\n")
+	fmt.Fprintf(&buf, "// Struct layouts on %s.\n", arch)
+
+	for _, info := range infos {
+		fmt.Fprintf(&buf, "\n#define %s__size %d\n", info.Name, info.OriginalSize)
+		for _, f := range info.Fields {
+			if f.IsPadding || f.Name == "_" {
+				continue
+			}
+			fmt.Fprintf(&buf, "#define %s_%s %d\n", info.Name, f.Name, f.Offset)
+		}
+	}
+	return buf.Bytes()
+}
+
+// AsmMismatch is a line of an assembly file using a numeric offset or
+// size that no longer matches the struct it names.
+type AsmMismatch struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+var (
+	// memory operands like 8(AX), 0x18(AX) or -16(SP)
+	asmOffset = regexp.MustCompile(`(-?(?:0[xX][0-9a-fA-F]+|\d+))\(`)
+	// immediates like $24 or $0x18
+	asmImmediate = regexp.MustCompile(`\$(0[xX][0-9a-fA-F]+|\d+)`)
+	// fields named in comments: Header.Size, Header_Size or Header__size,
+	// split by asmSplit as type names may contain underscores too
+	asmReference = regexp.MustCompile(`\b[A-Za-z_]\w*(?:\.[A-Za-z_]\w*)?\b`)
+)
+
+// CheckAsm scans a hand-written assembly file for numeric offsets
+// documented by a comment naming the field, like
+//
+//	MOVQ 8(AX), BX // Header.Size
+//
+// and reports the ones that differ from the layout of the struct on arch.
+// Sizes are checked the same way for immediates next to T__size. Lines
+// naming a different number of fields than they use offsets are skipped
+// as ambiguous.
+func CheckAsm(path, arch string, infos []structi.Info) ([]AsmMismatch, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %v", path, err)
+	}
+
+	offsets := make(map[string]int64)
+	sizes := make(map[string]int64)
+	for _, info := range infos {
+		sizes[info.Name] = info.OriginalSize
+		for _, f := range info.Fields {
+			if !f.IsPadding {
+				offsets[info.Name+"."+f.Name] = f.Offset
+			}
+		}
+	}
+
+	var mismatches []AsmMismatch
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		code, comment, ok := strings.Cut(scanner.Text(), "//")
+		if !ok {
+			continue
+		}
+
+		var fieldRefs, sizeRefs []string
+		for _, ref := range asmReference.FindAllString(comment, -1) {
+			typ, field, ok := asmSplit(ref, sizes, offsets)
+			switch {
+			case !ok:
+			case field == "":
+				sizeRefs = append(sizeRefs, typ)
+			default:
+				fieldRefs = append(fieldRefs, typ+"."+field)
+			}
+		}
+
+		used := asmOffset.FindAllStringSubmatch(code, -1)
+		if len(fieldRefs) > 0 && len(fieldRefs) == len(used) {
+			for i, ref := range fieldRefs {
+				n, _ := strconv.ParseInt(used[i][1], 0, 64)
+				if want := offsets[ref]; n != want {
+					mismatches = append(mismatches, AsmMismatch{
+						File:    path,
+						Line:    line,
+						Message: fmt.Sprintf("%s is at offset %d on %s, not %d", ref, want, arch, n),
+					})
+				}
+			}
+		}
+
+		immediates := asmImmediate.FindAllStringSubmatch(code, -1)
+		if len(sizeRefs) > 0 && len(sizeRefs) == len(immediates) {
+			for i, ref := range sizeRefs {
+				n, _ := strconv.ParseInt(immediates[i][1], 0, 64)
+				if want := sizes[ref]; n != want {
+					mismatches = append(mismatches, AsmMismatch{
+						File:    path,
+						Line:    line,
+						Message: fmt.Sprintf("%s is %d bytes on %s, not %d", ref, want, arch, n),
+					})
+				}
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading %s: %v", path, err)
+	}
+
+	return mismatches, nil
+}
+
+// asmSplit splits a reference into the struct and the field it names, an
+// empty field for T__size. Underscore separated references are tried at
+// every underscore until the prefix is a known struct with that field.
+func asmSplit(ref string, sizes, offsets map[string]int64) (string, string, bool) {
+	if typ, field, ok := strings.Cut(ref, "."); ok {
+		_, known := offsets[typ+"."+field]
+		return typ, field, known
+	}
+
+	for i := strings.Index(ref, "_"); i >= 0; i = nextUnderscore(ref, i) {
+		typ, rest := ref[:i], ref[i+1:]
+		if _, ok := sizes[typ]; !ok {
+			continue
+		}
+		if rest == "_size" {
+			return typ, "", true
+		}
+		if _, ok := offsets[typ+"."+rest]; ok {
+			return typ, rest, true
+		}
+	}
+	return "", "", false
+}
+
+func nextUnderscore(s string, i int) int {
+	j := strings.Index(s[i+1:], "_")
+	if j < 0 {
+		return -1
+	}
+	return i + 1 + j
+}
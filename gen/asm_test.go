@@ -0,0 +1,156 @@
+package gen
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/buarki/viztruct/structi"
+)
+
+func header(t *testing.T) []structi.Info {
+	t.Helper()
+	infos, err := structi.AnalyseStructs(`package main
+
+type Header struct {
+	Magic uint32
+	_     uint32
+	Size  uint64
+}
+`)
+	if err != nil {
+		t.Fatalf("analyse error: %v", err)
+	}
+	return infos
+}
+
+func TestAsmHeader(t *testing.T) {
+	got := string(AsmHeader("amd64", header(t)))
+	for _, want := range []string{
+		"#define Header__size 16\n",
+		"#define Header_Magic 0\n",
+		"#define Header_Size 8\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q in\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "#define Header__ ") {
+		t.Errorf("blank fields have no constant:\n%s", got)
+	}
+}
+
+func TestCheckAsm(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "load_amd64.s")
+	src := `#include "textflag.h"
+
+TEXT ·load(SB), NOSPLIT, $0-16
+	MOVQ h+0(FP), AX
+	MOVQ 8(AX), BX // Header.Size
+	MOVL 4(AX), CX // Header.Magic
+	MOVQ 16(AX), DX // Header.Size and Header.Magic: ambiguous
+	SUBQ $24, SP // Header__size
+	MOVQ 8(AX), BX // unrelated comment
+	RET
+`
+	if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	mismatches, err := CheckAsm(path, "amd64", header(t))
+	if err != nil {
+		t.Fatalf("check error: %v", err)
+	}
+
+	var got []string
+	for _, m := range mismatches {
+		got = append(got, m.Message)
+		if m.File != path {
+			t.Errorf("unexpected file %s", m.File)
+		}
+	}
+	want := []string{
+		"Header.Magic is at offset 0 on amd64, not 4",
+		"Header is 16 bytes on amd64, not 24",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got\n%v\nwant\n%v", got, want)
+	}
+	if mismatches[0].Line != 6 || mismatches[1].Line != 8 {
+		t.Errorf("unexpected lines %d and %d", mismatches[0].Line, mismatches[1].Line)
+	}
+}
+
+func TestCheckAsmUnderscoreNames(t *testing.T) {
+	infos, err := structi.AnalyseStructs(`package main
+
+type ring_buf struct {
+	head     uint32
+	tail_pos uint64
+}
+`)
+	if err != nil {
+		t.Fatalf("analyse error: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "ring_amd64.s")
+	src := `TEXT ·pop(SB), NOSPLIT, $0-8
+	MOVQ 4(AX), BX // ring_buf_tail_pos(AX)
+	MOVL 0(AX), CX // ring_buf.head
+	SUBQ $12, SP // ring_buf__size
+	RET
+`
+	if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	mismatches, err := CheckAsm(path, "amd64", infos)
+	if err != nil {
+		t.Fatalf("check error: %v", err)
+	}
+
+	var got []string
+	for _, m := range mismatches {
+		got = append(got, m.Message)
+	}
+	want := []string{
+		"ring_buf.tail_pos is at offset 8 on amd64, not 4",
+		"ring_buf is 16 bytes on amd64, not 12",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got\n%v\nwant\n%v", got, want)
+	}
+}
+
+func TestCheckAsmHex(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "load_amd64.s")
+	src := `TEXT ·load(SB), NOSPLIT, $0-16
+	MOVQ 0x8(AX), BX // Header.Size
+	MOVL 0x4(AX), CX // Header.Magic
+	SUBQ $0x10, SP // Header__size
+	ADDQ $0x18, SP // Header__size
+	RET
+`
+	if err := os.WriteFile(path, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	mismatches, err := CheckAsm(path, "amd64", header(t))
+	if err != nil {
+		t.Fatalf("check error: %v", err)
+	}
+
+	var got []string
+	for _, m := range mismatches {
+		got = append(got, m.Message)
+	}
+	want := []string{
+		"Header.Magic is at offset 0 on amd64, not 4",
+		"Header is 16 bytes on amd64, not 24",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got\n%v\nwant\n%v", got, want)
+	}
+}
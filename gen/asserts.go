@@ -0,0 +1,60 @@
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+
+	"github.com/buarki/viztruct/structi"
+)
+
+// IgnoreTag is the build tag excluding generated files, packages are
+// loaded with it while generating so stale assertions do not prevent the
+// analysis.
+const IgnoreTag = "viztruct"
+
+// AssertsFile is the name of the file holding the assertions of arch.
+func AssertsFile(arch string) string {
+	return fmt.Sprintf("viztruct_layout_%s.go", arch)
+}
+
+// Assertions generates a Go file of package pkgName failing to compile on
+// arch as soon as the size of one of the structs or the offset of one of
+// their fields differs from the one in infos. Each check indexes a one
+// element array with the difference, which is out of range or overflows
+// unless it is zero.
+func Assertions(pkgName, arch string, infos []structi.Info) ([]byte, error) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by \"viztruct asserts\"; DO NOT EDIT.\n\n")
+	fmt.Fprintf(&buf, "//go:build %s && !%s\n\n", arch, IgnoreTag)
+	fmt.Fprintf(&buf, "package %s\n\n", pkgName)
+	fmt.Fprintf(&buf, "import \"unsafe\"\n\n")
+	fmt.Fprintf(&buf, "// The layouts below were verified with viztruct on %s, regenerate this\n", arch)
+	fmt.Fprintf(&buf, "// file after changing them on purpose.\n")
+	fmt.Fprintf(&buf, "func _() {\n\tvar x [1]struct{}\n")
+
+	for _, info := range infos {
+		fmt.Fprintf(&buf, "\n\t// %s: %d bytes\n", info.Name, info.OriginalSize)
+		fmt.Fprintf(&buf, "\t_ = x[%s]\n", minus(fmt.Sprintf("unsafe.Sizeof(%s{})", info.Name), info.OriginalSize))
+		for _, f := range info.Fields {
+			if f.IsPadding || f.Name == "_" {
+				continue
+			}
+			fmt.Fprintf(&buf, "\t_ = x[%s]\n", minus(fmt.Sprintf("unsafe.Offsetof(%s{}.%s)", info.Name, f.Name), f.Offset))
+		}
+	}
+	buf.WriteString("}\n")
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("error formatting generated assertions: %v", err)
+	}
+	return src, nil
+}
+
+func minus(expr string, n int64) string {
+	if n == 0 {
+		return expr
+	}
+	return fmt.Sprintf("%s-%d", expr, n)
+}
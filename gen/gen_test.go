@@ -0,0 +1,83 @@
+package gen
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/buarki/viztruct/internal/testmod"
+	"github.com/buarki/viztruct/structi"
+)
+
+func goVet(dir string) (string, error) {
+	cmd := exec.Command("go", "vet", ".")
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	return string(out), err
+}
+
+func TestAssertions(t *testing.T) {
+	dir := testmod.Write(t, map[string]string{
+		"a.go": `package sample
+
+type Header struct {
+	Magic uint32
+	_     uint16
+	Size  uint64
+}
+`,
+	})
+
+	pkgs, err := structi.LoadPackages(structi.LoadConfig{Dir: dir, Tags: []string{IgnoreTag}})
+	if err != nil {
+		t.Fatalf("load error: %v", err)
+	}
+	infos, err := structi.AnalysePackages(pkgs)
+	if err != nil {
+		t.Fatalf("analyse error: %v", err)
+	}
+
+	src, err := Assertions("sample", runtime.GOARCH, infos)
+	if err != nil {
+		t.Fatalf("generate error: %v", err)
+	}
+	for _, want := range []string{
+		"//go:build " + runtime.GOARCH + " && !viztruct",
+		"_ = x[unsafe.Sizeof(Header{})-16]",
+		"_ = x[unsafe.Offsetof(Header{}.Magic)]",
+		"_ = x[unsafe.Offsetof(Header{}.Size)-8]",
+	} {
+		if !strings.Contains(string(src), want) {
+			t.Errorf("missing %q in\n%s", want, src)
+		}
+	}
+	if strings.Contains(string(src), "Header{}._") {
+		t.Errorf("blank fields cannot be asserted:\n%s", src)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, AssertsFile(runtime.GOARCH)), src, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := goVet(dir); err != nil {
+		t.Fatalf("generated assertions do not compile: %v\n%s", err, out)
+	}
+
+	// moving a field must break the build
+	moved := `package sample
+
+type Header struct {
+	Size  uint64
+	Magic uint32
+	_     uint16
+}
+`
+	if err := os.WriteFile(filepath.Join(dir, "a.go"), []byte(moved), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := goVet(dir); err == nil {
+		t.Errorf("changed layout still compiles:\n%s", out)
+	}
+}
@@ -0,0 +1,120 @@
+// Package grid lays struct fields out as a memory dump: rows of a fixed
+// number of bytes, each field spanning the cells of the bytes it takes.
+package grid
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/buarki/viztruct/structi"
+)
+
+// CacheLineSize is the row size of the cache line grid.
+const CacheLineSize = 64
+
+// Cell is the part of a field falling in one row.
+type Cell struct {
+	Name     string
+	TypeName string
+	Kind     structi.Kind
+	// Offset and Size of the bytes of the field in the row
+	Offset    int64
+	Size      int64
+	IsPadding bool
+	// Continued is set when the field started on a previous row.
+	Continued bool
+}
+
+type Row struct {
+	Offset int64
+	Cells  []Cell
+}
+
+// ParseRowBytes reads the size of the rows: "word" for the word size of
+// the architecture, "cacheline" for CacheLineSize or a number of bytes.
+func ParseRowBytes(s string, wordSize int64) (int64, error) {
+	switch s {
+	case "word":
+		return wordSize, nil
+	case "cacheline":
+		return CacheLineSize, nil
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid grid: %s. use 'word', 'cacheline' or a number of bytes", s)
+	}
+	return n, nil
+}
+
+// Rows splits laid out fields into rows of rowBytes bytes. Zero sized
+// fields take no cell.
+func Rows(fields []structi.Field, rowBytes int64) []Row {
+	var rows []Row
+	for _, f := range fields {
+		offset, size := f.Offset, f.Size
+		for size > 0 {
+			start := offset / rowBytes * rowBytes
+			if len(rows) == 0 || rows[len(rows)-1].Offset != start {
+				rows = append(rows, Row{Offset: start})
+			}
+			n := min(size, start+rowBytes-offset)
+			row := &rows[len(rows)-1]
+			row.Cells = append(row.Cells, Cell{
+				Name:      f.Name,
+				TypeName:  f.TypeName,
+				Kind:      f.Kind,
+				Offset:    offset,
+				Size:      n,
+				IsPadding: f.IsPadding,
+				Continued: offset != f.Offset,
+			})
+			offset, size = offset+n, size-n
+		}
+	}
+	return rows
+}
+
+// Format renders the grid of fields for a terminal, padding hatched:
+//
+//	offset  0   1   2   3   4   5   6   7
+//	     0 |A  |///////////////////////////|
+//	     8 |B                              |
+func Format(fields []structi.Field, rowBytes int64) string {
+	// narrower cells keep cache line rows within a terminal
+	cellWidth := int64(4)
+	if rowBytes > 16 {
+		cellWidth = 2
+	}
+
+	header := "offset "
+	for col := int64(0); col < rowBytes; col++ {
+		if cellWidth == 4 {
+			header += fmt.Sprintf(" %-3d", col)
+		} else if col%8 == 0 {
+			header += fmt.Sprintf(" %-15d", col)
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString(strings.TrimRight(header, " ") + "\n")
+
+	for _, row := range Rows(fields, rowBytes) {
+		fmt.Fprintf(&sb, "%6d |", row.Offset)
+		for _, c := range row.Cells {
+			width := int(c.Size*cellWidth - 1)
+			if c.IsPadding {
+				sb.WriteString(strings.Repeat("/", width))
+			} else {
+				label := c.Name
+				if len(label) > width {
+					label = label[:width]
+				}
+				sb.WriteString(label + strings.Repeat(" ", width-len(label)))
+			}
+			sb.WriteString("|")
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
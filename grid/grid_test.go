@@ -0,0 +1,76 @@
+package grid
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/buarki/viztruct/structi"
+)
+
+func analyse(t *testing.T, src string) structi.Info {
+	t.Helper()
+	infos, err := structi.AnalyseStructs(src)
+	if err != nil {
+		t.Fatalf("analyse error: %v", err)
+	}
+	return infos[0]
+}
+
+func TestRows(t *testing.T) {
+	info := analyse(t, `type T struct {
+	A bool
+	B [12]byte
+	C int64
+}`)
+
+	rows := Rows(info.Fields, 8)
+	if len(rows) != 3 {
+		t.Fatalf("got %d rows, want 3", len(rows))
+	}
+
+	// B spans bytes 1 to 13 and the padding after it the rest of row 8
+	second := rows[1].Cells
+	if len(second) != 2 || second[0].Name != "B" || !second[0].Continued || second[0].Size != 5 || !second[1].IsPadding || second[1].Size != 3 {
+		t.Errorf("unexpected second row %+v", second)
+	}
+	if rows[2].Offset != 16 || len(rows[2].Cells) != 1 || rows[2].Cells[0].Name != "C" {
+		t.Errorf("unexpected last row %+v", rows[2])
+	}
+
+	if rows := Rows(info.Fields, CacheLineSize); len(rows) != 1 || len(rows[0].Cells) != 4 {
+		t.Errorf("got %+v on a cache line", rows)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	info := analyse(t, `type T struct {
+	A bool
+	B int64
+}`)
+
+	want := `offset  0   1   2   3   4   5   6   7
+     0 |A  |///////////////////////////|
+     8 |B                              |
+`
+	if got := Format(info.Fields, 8); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+
+	cacheLine := Format(info.Fields, CacheLineSize)
+	if lines := strings.Split(strings.TrimSpace(cacheLine), "\n"); len(lines) != 2 || !strings.HasSuffix(lines[1], "|B              |") {
+		t.Errorf("unexpected cache line grid\n%s", cacheLine)
+	}
+}
+
+func TestParseRowBytes(t *testing.T) {
+	for s, want := range map[string]int64{"word": 4, "cacheline": 64, "16": 16} {
+		if got, err := ParseRowBytes(s, 4); err != nil || got != want {
+			t.Errorf("got %d, %v for %s", got, err, s)
+		}
+	}
+	for _, s := range []string{"", "0", "line"} {
+		if _, err := ParseRowBytes(s, 8); err == nil {
+			t.Errorf("expected an error for %q", s)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package heapprof
+
+import (
+	"bytes"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"testing"
+
+	"github.com/buarki/viztruct/structi"
+)
+
+type profiled struct {
+	A bool
+	B int64
+	C bool
+}
+
+var sink []*profiled
+
+func allocateProfiled(n int) {
+	for i := 0; i < n; i++ {
+		sink = append(sink, &profiled{A: true})
+	}
+}
+
+func TestParseAndRankRealProfile(t *testing.T) {
+	old := runtime.MemProfileRate
+	runtime.MemProfileRate = 1
+	defer func() { runtime.MemProfileRate = old }()
+
+	allocateProfiled(1000)
+	runtime.GC()
+
+	var buf bytes.Buffer
+	if err := pprof.Lookup("allocs").WriteTo(&buf, 0); err != nil {
+		t.Fatalf("failed to write profile: %v", err)
+	}
+
+	profile, err := Parse(&buf)
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	if profile.SampleIndex("alloc_objects") < 0 || profile.SampleIndex("alloc_space") < 0 {
+		t.Fatalf("unexpected sample types: %+v", profile.SampleTypes)
+	}
+
+	structs, err := structi.AnalyseStructs(`type profiled struct {
+		A bool
+		B int64
+		C bool
+	}`)
+	if err != nil {
+		t.Fatalf("analyse error: %v", err)
+	}
+
+	wd, _ := os.Getwd()
+	ranking, err := Rank(structs, profile, NewSourceResolver(wd))
+	if err != nil {
+		t.Fatalf("rank error: %v", err)
+	}
+
+	if len(ranking) != 1 {
+		t.Fatalf("expected 1 ranked struct, got %d", len(ranking))
+	}
+	w := ranking[0]
+	if w.Name != "profiled" {
+		t.Errorf("expected struct profiled, got %q", w.Name)
+	}
+	if w.Objects < 1000 {
+		t.Errorf("expected at least 1000 objects, got %d", w.Objects)
+	}
+	// 24 bytes down to 16 bytes, both exact size classes
+	if w.SavedBytes != w.Objects*8 {
+		t.Errorf("expected %d saved bytes, got %d", w.Objects*8, w.SavedBytes)
+	}
+	if w.WastedBytes != w.Objects*14 {
+		t.Errorf("expected %d wasted bytes, got %d", w.Objects*14, w.WastedBytes)
+	}
+}
+
+func TestRankSizeClassAware(t *testing.T) {
+	// 40 bytes optimized to 33 bytes stays in the 48 byte class
+	info := structi.Info{Name: "T", OriginalSize: 40, OptimizedSize: 33, WastedBytes: 7}
+
+	tests := []struct {
+		name      string
+		kind      AllocKind
+		objects   int64
+		space     int64
+		wantSaved int64
+	}{
+		{name: "single objects", kind: AllocObject, objects: 10, space: 480, wantSaved: 0},
+		{name: "slice backing array", kind: AllocSlice, objects: 1, space: 400, wantSaved: 70},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, saved := allocWaste(info, tt.kind, tt.objects, tt.space)
+			if saved != tt.wantSaved {
+				t.Errorf("saved = %d, want %d", saved, tt.wantSaved)
+			}
+		})
+	}
+}
+
+func TestParseRejectsGarbage(t *testing.T) {
+	if _, err := Parse(bytes.NewReader([]byte{0x0a, 0xff})); err == nil {
+		t.Error("expected error for truncated profile")
+	}
+}
@@ -0,0 +1,363 @@
+package heapprof
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Profile is the subset of a pprof profile.proto needed to weight struct
+// waste by allocations. Only the fields written by the Go runtime heap
+// profiler are decoded.
+type Profile struct {
+	SampleTypes []ValueType
+	Samples     []Sample
+}
+
+type ValueType struct {
+	Type string
+	Unit string
+}
+
+type Sample struct {
+	// Stack holds the call stack with the leaf frame first. Inlined
+	// frames are expanded, innermost first.
+	Stack     []Frame
+	Values    []int64
+	NumLabels map[string][]int64
+}
+
+type Frame struct {
+	Function string
+	File     string
+	Line     int64
+}
+
+// SampleIndex returns the position of the sample type with the given name
+// in Sample.Values, or -1 if the profile does not have it.
+func (p *Profile) SampleIndex(name string) int {
+	for i, st := range p.SampleTypes {
+		if st.Type == name {
+			return i
+		}
+	}
+	return -1
+}
+
+var errTruncated = errors.New("truncated protobuf message")
+
+type rawValueType struct {
+	typ, unit int64
+}
+
+type rawSample struct {
+	locationIDs []uint64
+	values      []int64
+	labels      []rawLabel
+}
+
+type rawLabel struct {
+	key, str, num int64
+}
+
+type rawLine struct {
+	functionID uint64
+	line       int64
+}
+
+type rawFunction struct {
+	name, filename int64
+}
+
+// Parse reads a profile in the profile.proto format, gzip compressed or not.
+func Parse(r io.Reader) (*Profile, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(2)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read profile: %v", err)
+	}
+
+	var src io.Reader = br
+	if magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress profile: %v", err)
+		}
+		defer gz.Close()
+		src = gz
+	}
+
+	data, err := io.ReadAll(src)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read profile: %v", err)
+	}
+
+	return decodeProfile(data)
+}
+
+func decodeProfile(data []byte) (*Profile, error) {
+	var (
+		sampleTypes []rawValueType
+		samples     []rawSample
+		locations   = make(map[uint64][]rawLine)
+		functions   = make(map[uint64]rawFunction)
+		strs        []string
+	)
+
+	err := walk(data, func(field int, wire int, v uint64, b []byte) error {
+		switch field {
+		case 1:
+			vt, err := decodeValueType(b)
+			if err != nil {
+				return err
+			}
+			sampleTypes = append(sampleTypes, vt)
+		case 2:
+			s, err := decodeSample(b)
+			if err != nil {
+				return err
+			}
+			samples = append(samples, s)
+		case 4:
+			id, lines, err := decodeLocation(b)
+			if err != nil {
+				return err
+			}
+			locations[id] = lines
+		case 5:
+			id, fn, err := decodeFunction(b)
+			if err != nil {
+				return err
+			}
+			functions[id] = fn
+		case 6:
+			strs = append(strs, string(b))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode profile: %v", err)
+	}
+
+	str := func(i int64) string {
+		if i < 0 || int(i) >= len(strs) {
+			return ""
+		}
+		return strs[i]
+	}
+
+	p := &Profile{}
+	for _, vt := range sampleTypes {
+		p.SampleTypes = append(p.SampleTypes, ValueType{Type: str(vt.typ), Unit: str(vt.unit)})
+	}
+
+	for _, rs := range samples {
+		s := Sample{Values: rs.values}
+		for _, id := range rs.locationIDs {
+			for _, l := range locations[id] {
+				fn := functions[l.functionID]
+				s.Stack = append(s.Stack, Frame{
+					Function: str(fn.name),
+					File:     str(fn.filename),
+					Line:     l.line,
+				})
+			}
+		}
+		for _, l := range rs.labels {
+			if l.str != 0 {
+				continue // only numeric labels are of interest
+			}
+			if s.NumLabels == nil {
+				s.NumLabels = make(map[string][]int64)
+			}
+			key := str(l.key)
+			s.NumLabels[key] = append(s.NumLabels[key], l.num)
+		}
+		p.Samples = append(p.Samples, s)
+	}
+
+	return p, nil
+}
+
+func decodeValueType(data []byte) (rawValueType, error) {
+	var vt rawValueType
+	err := walk(data, func(field int, wire int, v uint64, b []byte) error {
+		switch field {
+		case 1:
+			vt.typ = int64(v)
+		case 2:
+			vt.unit = int64(v)
+		}
+		return nil
+	})
+	return vt, err
+}
+
+func decodeSample(data []byte) (rawSample, error) {
+	var s rawSample
+	err := walk(data, func(field int, wire int, v uint64, b []byte) error {
+		switch field {
+		case 1:
+			ids, err := repeatedVarint(wire, v, b)
+			if err != nil {
+				return err
+			}
+			s.locationIDs = append(s.locationIDs, ids...)
+		case 2:
+			vals, err := repeatedVarint(wire, v, b)
+			if err != nil {
+				return err
+			}
+			for _, val := range vals {
+				s.values = append(s.values, int64(val))
+			}
+		case 3:
+			l, err := decodeLabel(b)
+			if err != nil {
+				return err
+			}
+			s.labels = append(s.labels, l)
+		}
+		return nil
+	})
+	return s, err
+}
+
+func decodeLabel(data []byte) (rawLabel, error) {
+	var l rawLabel
+	err := walk(data, func(field int, wire int, v uint64, b []byte) error {
+		switch field {
+		case 1:
+			l.key = int64(v)
+		case 2:
+			l.str = int64(v)
+		case 3:
+			l.num = int64(v)
+		}
+		return nil
+	})
+	return l, err
+}
+
+func decodeLocation(data []byte) (uint64, []rawLine, error) {
+	var (
+		id    uint64
+		lines []rawLine
+	)
+	err := walk(data, func(field int, wire int, v uint64, b []byte) error {
+		switch field {
+		case 1:
+			id = v
+		case 4:
+			var l rawLine
+			err := walk(b, func(field int, wire int, v uint64, b []byte) error {
+				switch field {
+				case 1:
+					l.functionID = v
+				case 2:
+					l.line = int64(v)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			lines = append(lines, l)
+		}
+		return nil
+	})
+	return id, lines, err
+}
+
+func decodeFunction(data []byte) (uint64, rawFunction, error) {
+	var (
+		id uint64
+		fn rawFunction
+	)
+	err := walk(data, func(field int, wire int, v uint64, b []byte) error {
+		switch field {
+		case 1:
+			id = v
+		case 2:
+			fn.name = int64(v)
+		case 4:
+			fn.filename = int64(v)
+		}
+		return nil
+	})
+	return id, fn, err
+}
+
+// repeatedVarint handles both the packed and the unpacked encoding of
+// repeated integer fields.
+func repeatedVarint(wire int, v uint64, b []byte) ([]uint64, error) {
+	if wire == 0 {
+		return []uint64{v}, nil
+	}
+	var out []uint64
+	for len(b) > 0 {
+		x, n := binary.Uvarint(b)
+		if n <= 0 {
+			return nil, errTruncated
+		}
+		out = append(out, x)
+		b = b[n:]
+	}
+	return out, nil
+}
+
+// walk iterates over the fields of a protobuf message calling fn with the
+// field number, the wire type and either the varint value or the bytes of
+// a length-delimited field.
+func walk(data []byte, fn func(field int, wire int, v uint64, b []byte) error) error {
+	r := bytes.NewReader(data)
+	for r.Len() > 0 {
+		key, err := binary.ReadUvarint(r)
+		if err != nil {
+			return errTruncated
+		}
+		field, wire := int(key>>3), int(key&7)
+
+		var (
+			v uint64
+			b []byte
+		)
+		switch wire {
+		case 0:
+			if v, err = binary.ReadUvarint(r); err != nil {
+				return errTruncated
+			}
+		case 1:
+			var buf [8]byte
+			if _, err := io.ReadFull(r, buf[:]); err != nil {
+				return errTruncated
+			}
+			v = binary.LittleEndian.Uint64(buf[:])
+		case 2:
+			n, err := binary.ReadUvarint(r)
+			if err != nil || n > uint64(r.Len()) {
+				return errTruncated
+			}
+			off := len(data) - r.Len()
+			b = data[off : off+int(n)]
+			r.Seek(int64(n), io.SeekCurrent)
+		case 5:
+			var buf [4]byte
+			if _, err := io.ReadFull(r, buf[:]); err != nil {
+				return errTruncated
+			}
+			v = uint64(binary.LittleEndian.Uint32(buf[:]))
+		default:
+			return fmt.Errorf("unsupported wire type %d", wire)
+		}
+
+		if err := fn(field, wire, v, b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,299 @@
+package heapprof
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/buarki/viztruct/structi"
+)
+
+// AllocKind tells how a struct value is allocated at a site.
+type AllocKind string
+
+const (
+	// AllocObject is a single value: new(T), &T{} or an escaping T{}.
+	AllocObject AllocKind = "object"
+	// AllocSlice is a backing array holding many values: make([]T, n).
+	AllocSlice AllocKind = "slice"
+	// AllocMap is a map storing values of the struct: make(map[K]T).
+	AllocMap AllocKind = "map"
+)
+
+// Alloc is a struct allocation found in the source at a profiled site.
+type Alloc struct {
+	TypeName string
+	Kind     AllocKind
+}
+
+// Resolver returns the struct allocations present at a source line.
+type Resolver interface {
+	Resolve(file string, line int64) []Alloc
+}
+
+// Weight is the production weight of a struct according to a heap profile.
+type Weight struct {
+	Name          string   `json:"name"`
+	OriginalSize  int64    `json:"original_size"`
+	OptimizedSize int64    `json:"optimized_size"`
+	Objects       int64    `json:"objects"`
+	AllocBytes    int64    `json:"alloc_bytes"`
+	WastedBytes   int64    `json:"wasted_bytes"`
+	SavedBytes    int64    `json:"saved_bytes"`
+	Sites         []string `json:"sites"`
+}
+
+// Rank maps the allocation sites of a heap profile to the given structs and
+// ranks them by the total bytes their optimized layout would have saved.
+// Savings of single objects are size-class aware: reordering a 40 byte
+// struct down to 33 bytes saves nothing as both land in the 48 byte class.
+func Rank(structs []structi.Info, p *Profile, r Resolver) ([]Weight, error) {
+	objIdx, spaceIdx := p.SampleIndex("alloc_objects"), p.SampleIndex("alloc_space")
+	if objIdx < 0 || spaceIdx < 0 {
+		return nil, fmt.Errorf("profile has no alloc_objects/alloc_space samples, is it a heap profile?")
+	}
+
+	byName := make(map[string]structi.Info)
+	for _, s := range structs {
+		byName[s.Name] = s
+	}
+
+	weights := make(map[string]*Weight)
+	for _, sample := range p.Samples {
+		if len(sample.Values) <= max(objIdx, spaceIdx) {
+			continue
+		}
+		frame, ok := allocationFrame(sample.Stack)
+		if !ok {
+			continue
+		}
+
+		alloc, ok := pickAlloc(r.Resolve(frame.File, frame.Line), byName, sample.NumLabels["bytes"])
+		if !ok {
+			continue
+		}
+		info := byName[alloc.TypeName]
+
+		w, ok := weights[info.Name]
+		if !ok {
+			w = &Weight{
+				Name:          info.Name,
+				OriginalSize:  info.OriginalSize,
+				OptimizedSize: info.OptimizedSize,
+			}
+			weights[info.Name] = w
+		}
+
+		objects, space := sample.Values[objIdx], sample.Values[spaceIdx]
+		wasted, saved := allocWaste(info, alloc.Kind, objects, space)
+
+		w.AllocBytes += space
+		w.WastedBytes += wasted
+		w.SavedBytes += saved
+		if alloc.Kind == AllocObject {
+			w.Objects += objects
+		} else if info.OriginalSize > 0 {
+			w.Objects += space / info.OriginalSize
+		}
+
+		site := fmt.Sprintf("%s:%d", frame.File, frame.Line)
+		if !contains(w.Sites, site) {
+			w.Sites = append(w.Sites, site)
+		}
+	}
+
+	var ranking []Weight
+	for _, w := range weights {
+		ranking = append(ranking, *w)
+	}
+	sort.Slice(ranking, func(i, j int) bool {
+		if ranking[i].SavedBytes != ranking[j].SavedBytes {
+			return ranking[i].SavedBytes > ranking[j].SavedBytes
+		}
+		return ranking[i].Name < ranking[j].Name
+	})
+
+	return ranking, nil
+}
+
+// allocWaste estimates the padding bytes allocated and the bytes an
+// optimized layout would have saved for a profile sample.
+func allocWaste(info structi.Info, kind AllocKind, objects, space int64) (int64, int64) {
+	wastedPerValue := info.WastedBytes
+	if kind == AllocObject {
+		saved := structi.SizeClass(info.OriginalSize) - structi.SizeClass(info.OptimizedSize)
+		return wastedPerValue * objects, saved * objects
+	}
+
+	// backing arrays hold values back to back, so every byte counts
+	if info.OriginalSize == 0 {
+		return 0, 0
+	}
+	values := space / info.OriginalSize
+	return wastedPerValue * values, (info.OriginalSize - info.OptimizedSize) * values
+}
+
+// allocationFrame returns the first frame outside the runtime, which is
+// where the program asked for the memory.
+func allocationFrame(stack []Frame) (Frame, bool) {
+	for _, f := range stack {
+		if strings.HasPrefix(f.Function, "runtime.") || f.File == "" {
+			continue
+		}
+		return f, true
+	}
+	return Frame{}, false
+}
+
+// pickAlloc chooses which of the allocations of a line a sample belongs to.
+// When a line allocates several known structs the object size recorded by
+// the profiler is used to tell them apart.
+func pickAlloc(allocs []Alloc, known map[string]structi.Info, blockSizes []int64) (Alloc, bool) {
+	var candidates []Alloc
+	for _, a := range allocs {
+		if _, ok := known[a.TypeName]; ok {
+			candidates = append(candidates, a)
+		}
+	}
+	if len(candidates) == 0 {
+		return Alloc{}, false
+	}
+
+	if len(candidates) > 1 && len(blockSizes) > 0 {
+		for _, a := range candidates {
+			if a.Kind == AllocObject && structi.SizeClass(known[a.TypeName].OriginalSize) == blockSizes[0] {
+				return a, true
+			}
+		}
+	}
+	return candidates[0], true
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// SourceResolver finds allocations by parsing the source files referenced
+// by the profile. Paths that do not exist locally are retried relative to
+// Root by dropping leading directories, so profiles taken on a build
+// machine can be resolved against a local checkout.
+type SourceResolver struct {
+	Root string
+
+	fset  *token.FileSet
+	files map[string]*ast.File
+}
+
+func NewSourceResolver(root string) *SourceResolver {
+	return &SourceResolver{
+		Root:  root,
+		fset:  token.NewFileSet(),
+		files: make(map[string]*ast.File),
+	}
+}
+
+func (s *SourceResolver) Resolve(file string, line int64) []Alloc {
+	node := s.parse(file)
+	if node == nil {
+		return nil
+	}
+
+	var allocs []Alloc
+	ast.Inspect(node, func(n ast.Node) bool {
+		if n == nil {
+			return false
+		}
+		start, end := int64(s.fset.Position(n.Pos()).Line), int64(s.fset.Position(n.End()).Line)
+		if line < start || line > end {
+			return false
+		}
+		if start != line {
+			return true
+		}
+
+		switch e := n.(type) {
+		case *ast.CompositeLit:
+			if name := typeIdent(e.Type); name != "" {
+				allocs = append(allocs, Alloc{TypeName: name, Kind: AllocObject})
+			}
+		case *ast.CallExpr:
+			fn, ok := e.Fun.(*ast.Ident)
+			if !ok || len(e.Args) == 0 {
+				return true
+			}
+			switch fn.Name {
+			case "new":
+				if name := typeIdent(e.Args[0]); name != "" {
+					allocs = append(allocs, Alloc{TypeName: name, Kind: AllocObject})
+				}
+			case "make":
+				switch t := e.Args[0].(type) {
+				case *ast.ArrayType:
+					if name := typeIdent(t.Elt); name != "" {
+						allocs = append(allocs, Alloc{TypeName: name, Kind: AllocSlice})
+					}
+				case *ast.MapType:
+					if name := typeIdent(t.Value); name != "" {
+						allocs = append(allocs, Alloc{TypeName: name, Kind: AllocMap})
+					}
+				}
+			}
+		}
+		return true
+	})
+
+	return allocs
+}
+
+func (s *SourceResolver) parse(file string) *ast.File {
+	if node, ok := s.files[file]; ok {
+		return node
+	}
+
+	var node *ast.File
+	if path := s.locate(file); path != "" {
+		node, _ = parser.ParseFile(s.fset, path, nil, parser.SkipObjectResolution)
+	}
+	s.files[file] = node
+	return node
+}
+
+func (s *SourceResolver) locate(file string) string {
+	if _, err := os.Stat(file); err == nil {
+		return file
+	}
+
+	parts := strings.Split(filepath.ToSlash(file), "/")
+	for i := 1; i < len(parts); i++ {
+		candidate := filepath.Join(s.Root, filepath.FromSlash(strings.Join(parts[i:], "/")))
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// typeIdent returns the name of a (possibly package qualified) named type.
+func typeIdent(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		return t.Name
+	case *ast.SelectorExpr:
+		return t.Sel.Name
+	case *ast.IndexExpr:
+		return typeIdent(t.X)
+	case *ast.IndexListExpr:
+		return typeIdent(t.X)
+	}
+	return ""
+}
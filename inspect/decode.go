@@ -0,0 +1,202 @@
+package inspect
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/buarki/viztruct/structi"
+)
+
+// Value is a field of a struct decoded from memory.
+type Value struct {
+	Name      string `json:"name"`
+	TypeName  string `json:"type,omitempty"`
+	Offset    int64  `json:"offset"`
+	Size      int64  `json:"size"`
+	IsPadding bool   `json:"is_padding"`
+	Hex       string `json:"hex"`
+	Value     string `json:"value"`
+	// NonZeroPadding flags padding bytes holding something else than zeroes,
+	// usually stale data leaking from a previous use of the memory.
+	NonZeroPadding bool `json:"non_zero_padding,omitempty"`
+}
+
+// ParseByteOrder accepts "little" and "big".
+func ParseByteOrder(name string) (binary.ByteOrder, error) {
+	switch strings.ToLower(name) {
+	case "little", "le":
+		return binary.LittleEndian, nil
+	case "big", "be":
+		return binary.BigEndian, nil
+	}
+	return nil, fmt.Errorf("invalid endianness %q, use 'little' or 'big'", name)
+}
+
+// Decode reads the fields of a struct from mem, which must start at the
+// first byte of the struct and hold at least its whole size.
+func Decode(info structi.Info, mem []byte, order binary.ByteOrder) ([]Value, error) {
+	if int64(len(mem)) < info.OriginalSize {
+		return nil, fmt.Errorf("%s needs %d bytes but the dump has %d", info.Name, info.OriginalSize, len(mem))
+	}
+
+	var values []Value
+	for _, f := range info.Fields {
+		raw := mem[f.Offset : f.Offset+f.Size]
+		v := Value{
+			Name:      f.Name,
+			TypeName:  f.TypeName,
+			Offset:    f.Offset,
+			Size:      f.Size,
+			IsPadding: f.IsPadding,
+			Hex:       hexBytes(raw),
+		}
+
+		if f.IsPadding {
+			v.NonZeroPadding = !allZero(raw)
+			if v.NonZeroPadding {
+				v.Value = "non-zero padding"
+			} else {
+				v.Value = "zero"
+			}
+		} else {
+			v.Value = decodeValue(f.TypeName, raw, order)
+		}
+		values = append(values, v)
+	}
+
+	return values, nil
+}
+
+func decodeValue(typeName string, raw []byte, order binary.ByteOrder) string {
+	size := len(raw)
+
+	switch typeName {
+	case "bool":
+		if size == 1 {
+			switch raw[0] {
+			case 0:
+				return "false"
+			case 1:
+				return "true"
+			}
+			return fmt.Sprintf("invalid bool (%d)", raw[0])
+		}
+	case "int", "int8", "int16", "int32", "int64", "rune":
+		if v, ok := readUint(raw, order); ok {
+			return strconv.FormatInt(signExtend(v, size), 10)
+		}
+	case "uint", "uint8", "uint16", "uint32", "uint64", "byte":
+		if v, ok := readUint(raw, order); ok {
+			return strconv.FormatUint(v, 10)
+		}
+	case "uintptr", "unsafe.Pointer":
+		if v, ok := readUint(raw, order); ok {
+			return fmt.Sprintf("0x%x", v)
+		}
+	case "float32":
+		if size == 4 {
+			return strconv.FormatFloat(float64(math.Float32frombits(order.Uint32(raw))), 'g', -1, 32)
+		}
+	case "float64":
+		if size == 8 {
+			return strconv.FormatFloat(math.Float64frombits(order.Uint64(raw)), 'g', -1, 64)
+		}
+	case "complex64":
+		if size == 8 {
+			re := math.Float32frombits(order.Uint32(raw[:4]))
+			im := math.Float32frombits(order.Uint32(raw[4:]))
+			return strconv.FormatComplex(complex(float64(re), float64(im)), 'g', -1, 64)
+		}
+	case "complex128":
+		if size == 16 {
+			re := math.Float64frombits(order.Uint64(raw[:8]))
+			im := math.Float64frombits(order.Uint64(raw[8:]))
+			return strconv.FormatComplex(complex(re, im), 'g', -1, 128)
+		}
+	case "string":
+		if words, ok := readWords(raw, 2, order); ok {
+			return fmt.Sprintf("ptr=0x%x len=%d", words[0], words[1])
+		}
+	}
+
+	switch {
+	case strings.HasPrefix(typeName, "*"), strings.HasPrefix(typeName, "map["),
+		strings.HasPrefix(typeName, "chan "), strings.HasPrefix(typeName, "func("):
+		if v, ok := readUint(raw, order); ok {
+			return fmt.Sprintf("0x%x", v)
+		}
+	case strings.HasPrefix(typeName, "[]"):
+		if words, ok := readWords(raw, 3, order); ok {
+			return fmt.Sprintf("ptr=0x%x len=%d cap=%d", words[0], words[1], words[2])
+		}
+	case strings.HasPrefix(typeName, "interface"), typeName == "any", typeName == "error":
+		if words, ok := readWords(raw, 2, order); ok {
+			return fmt.Sprintf("type=0x%x data=0x%x", words[0], words[1])
+		}
+	case strings.HasSuffix(typeName, "]byte"), strings.HasSuffix(typeName, "]uint8"):
+		return strconv.Quote(string(raw))
+	}
+
+	// nested structs, arrays and named types are shown as raw bytes
+	return hexBytes(raw)
+}
+
+func readUint(raw []byte, order binary.ByteOrder) (uint64, bool) {
+	switch len(raw) {
+	case 1:
+		return uint64(raw[0]), true
+	case 2:
+		return uint64(order.Uint16(raw)), true
+	case 4:
+		return uint64(order.Uint32(raw)), true
+	case 8:
+		return order.Uint64(raw), true
+	}
+	return 0, false
+}
+
+func signExtend(v uint64, size int) int64 {
+	shift := 64 - uint(size)*8
+	return int64(v<<shift) >> shift
+}
+
+// readWords splits raw into n pointer sized words.
+func readWords(raw []byte, n int, order binary.ByteOrder) ([]uint64, bool) {
+	if len(raw)%n != 0 {
+		return nil, false
+	}
+	wordSize := len(raw) / n
+	words := make([]uint64, n)
+	for i := range words {
+		w, ok := readUint(raw[i*wordSize:(i+1)*wordSize], order)
+		if !ok {
+			return nil, false
+		}
+		words[i] = w
+	}
+	return words, true
+}
+
+func allZero(raw []byte) bool {
+	for _, b := range raw {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func hexBytes(raw []byte) string {
+	var sb strings.Builder
+	for i, b := range raw {
+		if i > 0 {
+			sb.WriteByte(' ')
+		}
+		sb.WriteString(hex.EncodeToString([]byte{b}))
+	}
+	return sb.String()
+}
@@ -0,0 +1,94 @@
+package inspect
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// xxd lines look like "00000010: 2a00 0000 0000 0000  *......."
+var xxdLine = regexp.MustCompile(`^\s*[0-9a-fA-F]+:\s`)
+
+// ParseDump turns a byte dump into the bytes it describes. It accepts the
+// default output of xxd, plain hex strings (optionally with 0x prefixes,
+// spaces, commas or new lines) and falls back to treating data as raw
+// binary content.
+func ParseDump(data []byte) ([]byte, error) {
+	text := strings.TrimSpace(string(data))
+	if text == "" {
+		return nil, fmt.Errorf("empty dump")
+	}
+
+	lines := strings.Split(text, "\n")
+	if xxdLine.MatchString(lines[0]) {
+		return parseXXD(lines)
+	}
+
+	if decoded, ok := parseHex(text); ok {
+		return decoded, nil
+	}
+
+	return data, nil
+}
+
+// ReadDump reads the dump stored in the file at dump, or decodes dump
+// itself when it is an inline hex string rather than a path.
+func ReadDump(dump string) ([]byte, error) {
+	content, err := os.ReadFile(dump)
+	if err == nil {
+		mem, err := ParseDump(content)
+		if err != nil {
+			return nil, fmt.Errorf("error reading dump: %v", err)
+		}
+		return mem, nil
+	}
+	if decoded, ok := parseHex(dump); ok && len(decoded) > 0 {
+		return decoded, nil
+	}
+	return nil, fmt.Errorf("error reading dump: %v", err)
+}
+
+func parseXXD(lines []string) ([]byte, error) {
+	var out []byte
+	for i, line := range lines {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if !xxdLine.MatchString(line) {
+			return nil, fmt.Errorf("line %d is not xxd output: %q", i+1, line)
+		}
+
+		hexPart := line[strings.Index(line, ":")+1:]
+		// the ASCII column is separated from the bytes by two spaces
+		if end := strings.Index(strings.TrimLeft(hexPart, " "), "  "); end >= 0 {
+			hexPart = strings.TrimLeft(hexPart, " ")[:end]
+		}
+
+		decoded, err := hex.DecodeString(strings.ReplaceAll(strings.TrimSpace(hexPart), " ", ""))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid hex: %v", i+1, err)
+		}
+		out = append(out, decoded...)
+	}
+	return out, nil
+}
+
+func parseHex(text string) ([]byte, bool) {
+	var clean bytes.Buffer
+	for _, field := range strings.FieldsFunc(text, func(r rune) bool {
+		return r == ' ' || r == '\t' || r == '\n' || r == '\r' || r == ','
+	}) {
+		field = strings.TrimPrefix(strings.TrimPrefix(field, "0x"), "0X")
+		clean.WriteString(field)
+	}
+
+	decoded, err := hex.DecodeString(clean.String())
+	if err != nil {
+		return nil, false
+	}
+	return decoded, true
+}
@@ -0,0 +1,139 @@
+package inspect
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/buarki/viztruct/structi"
+)
+
+func TestParseDump(t *testing.T) {
+	want := []byte{0x01, 0x00, 0x02, 0xff}
+
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{name: "hex string", input: "010002ff"},
+		{name: "spaced hex", input: "01 00 02 ff\n"},
+		{name: "prefixed hex", input: "0x01, 0x00, 0x02, 0xff"},
+		{name: "xxd output", input: "00000000: 0100 02ff                                ....\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseDump([]byte(tt.input))
+			if err != nil {
+				t.Fatalf("parse error: %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("got % x, want % x", got, want)
+			}
+		})
+	}
+
+	raw := []byte{0x00, 0xde, 0xad}
+	got, err := ParseDump(raw)
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	if !reflect.DeepEqual(got, raw) {
+		t.Errorf("raw content should be kept as is, got % x", got)
+	}
+}
+
+func TestReadDump(t *testing.T) {
+	want := []byte{0x01, 0x00, 0x02, 0xff}
+
+	path := filepath.Join(t.TempDir(), "dump.xxd")
+	if err := os.WriteFile(path, []byte("00000000: 0100 02ff  ....\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, dump := range []string{path, "0x01 0x00 0x02 0xff"} {
+		got, err := ReadDump(dump)
+		if err != nil {
+			t.Fatalf("ReadDump(%q) error: %v", dump, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("ReadDump(%q) = % x, want % x", dump, got, want)
+		}
+	}
+
+	// a missing file must not be decoded as the bytes of its name
+	if got, err := ReadDump(filepath.Join(t.TempDir(), "dump.bin")); err == nil {
+		t.Errorf("expected an error for a missing file, got % x", got)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	infos, err := structi.AnalyseStructs(`type Header struct {
+		Valid bool
+		Count int32
+		Ratio float64
+		Tag   [4]byte
+		Delta int16
+	}`)
+	if err != nil {
+		t.Fatalf("analyse error: %v", err)
+	}
+
+	mem := []byte{
+		0x01, 0xaa, 0x00, 0x00, // Valid + dirty padding
+		0xfe, 0xff, 0xff, 0xff, // Count = -2
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xf8, 0x3f, // Ratio = 1.5
+		'a', 'b', 'c', 'd', // Tag
+		0x10, 0x00, // Delta = 16
+		0x00, 0x00, // tail padding
+	}
+
+	values, err := Decode(infos[0], mem, binary.LittleEndian)
+	if err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+
+	want := []struct {
+		name, value string
+		dirty       bool
+	}{
+		{name: "Valid", value: "true"},
+		{name: "padding", value: "non-zero padding", dirty: true},
+		{name: "Count", value: "-2"},
+		{name: "Ratio", value: "1.5"},
+		{name: "Tag", value: `"abcd"`},
+		{name: "Delta", value: "16"},
+		{name: "tail padding", value: "zero"},
+	}
+
+	if len(values) != len(want) {
+		t.Fatalf("expected %d values, got %d", len(want), len(values))
+	}
+	for i, w := range want {
+		v := values[i]
+		if v.Name != w.name || v.Value != w.value || v.NonZeroPadding != w.dirty {
+			t.Errorf("value[%d] = %+v, want %s=%s", i, v, w.name, w.value)
+		}
+	}
+
+	if _, err := Decode(infos[0], mem[:10], binary.LittleEndian); err == nil {
+		t.Error("expected error for short dump")
+	}
+}
+
+func TestDecodeBigEndian(t *testing.T) {
+	infos, err := structi.AnalyseStructs(`type T struct { A uint16; B uint16 }`)
+	if err != nil {
+		t.Fatalf("analyse error: %v", err)
+	}
+
+	values, err := Decode(infos[0], []byte{0x01, 0x02, 0x00, 0xff}, binary.BigEndian)
+	if err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if values[0].Value != "258" || values[1].Value != "255" {
+		t.Errorf("unexpected values %q %q", values[0].Value, values[1].Value)
+	}
+}
@@ -0,0 +1,28 @@
+// Package testmod writes throwaway Go modules for the tests that load or
+// build packages.
+package testmod
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Write creates the module example.com/sample in a temporary directory
+// with the given files, keyed by their slash separated path, and returns
+// its root.
+func Write(t testing.TB, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	files["go.mod"] = "module example.com/sample\n\ngo 1.21\n"
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
@@ -0,0 +1,31 @@
+package template
+
+var (
+	StructDiffTemplate = `{{define "struct_diff"}}
+<svg width="{{.Width}}" height="{{.Height}}" xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink">
+		<style>{{.Style}}</style>
+		<rect width="100%" height="100%" class="background"/>
+{{range .Changes}}
+<g>
+	<text x="10" y="{{add .Y 20.0}}" class="struct-name">{{.Name}}</text>
+	<text x="10" y="{{add .Y 40.0}}" class="field-text">{{.Summary}}</text>
+	{{$y := .Y}}
+	{{range .Columns}}
+	<text x="{{.X}}" y="{{add $y 70.0}}" class="field-text">{{.Title}}</text>
+	{{range .Blocks}}
+	<rect x="{{.X}}" y="{{add $y 85.0}}" width="{{.Width}}" height="{{.BlockHeight}}" class="{{.Class}} {{if .IsPadding}}padding-block{{else}}block{{end}}"/>
+	{{end}}
+	{{if .Blocks}}
+	<text x="{{.X}}" y="{{add $y 140.0}}" class="offset-text" text-anchor="start">0</text>
+	<text x="{{.EndX}}" y="{{add $y 140.0}}" class="offset-text" text-anchor="end">{{.Size}}</text>
+	{{end}}
+	{{$x := .X}}
+	{{range $i, $l := .Lines}}
+	<text x="{{$x}}" y="{{add $y (add 165.0 (mul (float64 $i) 15.0))}}" class="{{if $l.Changed}}changed-text{{else}}field-text{{end}}">{{$l.Text}}</text>
+	{{end}}
+	{{end}}
+</g>
+{{end}}
+</svg>
+{{end}}`
+)
@@ -0,0 +1,22 @@
+package template
+
+var (
+	StructGridTemplate = `{{define "struct_grid"}}
+	<text x="10" y="50" class="struct-name">{{.Name}}</text>
+<text x="10" y="70" class="field-text">Total size: {{.TotalSize}} bytes | Wasted: {{.WastedBytes}} bytes ({{.WastedPercent}}%) | Optimized: {{.OptimizedSize}} bytes</text>
+{{range .Grids}}
+<text x="10" y="{{.TitleY}}" class="field-text">{{.Title}}</text>
+{{$columnsY := .ColumnsY}}
+{{range .Columns}}
+<text x="{{.X}}" y="{{$columnsY}}" class="offset-text" text-anchor="start">{{.Offset}}</text>
+{{end}}
+{{range .Rows}}
+<text x="{{.X}}" y="{{.Y}}" class="offset-text" text-anchor="end">{{.Offset}}</text>
+{{end}}
+{{range .Cells}}
+<rect x="{{.X}}" y="{{.Y}}" width="{{.Width}}" height="{{.Height}}" class="{{if .IsPadding}}hatched{{else}}{{.Class}}{{end}} {{if .IsPadding}}grid-padding{{else}}block{{end}}"><title>{{.Title}}</title></rect>
+{{if .ShowName}}<text x="{{add .X 4.0}}" y="{{add .Y 19.0}}" class="field-text">{{.Name}}</text>{{end}}
+{{end}}
+{{end}}
+{{end}}`
+)
@@ -0,0 +1,104 @@
+package lint
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/buarki/viztruct/structi"
+)
+
+// BaselineEntry is a struct as printed by the json output format along
+// with the hash of its layout, so the json output of a previous run can be
+// used as a baseline as well.
+type BaselineEntry struct {
+	structi.Info
+	LayoutHash string `json:"layout_hash"`
+}
+
+// Baseline holds the structs of an accepted state of the code. Findings
+// on structs whose layout did not change since are not reported again.
+type Baseline struct {
+	Structs []BaselineEntry
+}
+
+func NewBaseline(infos []structi.Info) *Baseline {
+	b := &Baseline{}
+	for _, info := range infos {
+		b.Structs = append(b.Structs, BaselineEntry{Info: info, LayoutHash: info.LayoutHash()})
+	}
+	return b
+}
+
+func ReadBaseline(path string) (*Baseline, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading baseline: %v", err)
+	}
+
+	b := &Baseline{}
+	if err := json.Unmarshal(data, &b.Structs); err != nil {
+		return nil, fmt.Errorf("error decoding baseline: %v", err)
+	}
+	for i := range b.Structs {
+		if b.Structs[i].LayoutHash == "" {
+			b.Structs[i].LayoutHash = b.Structs[i].Info.LayoutHash()
+		}
+	}
+	return b, nil
+}
+
+func (b *Baseline) Write(path string) error {
+	data, err := json.MarshalIndent(b.Structs, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding baseline: %v", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("error writing baseline: %v", err)
+	}
+	return nil
+}
+
+// Filter drops the findings already present in the baseline. A finding
+// is kept when it breaks a maxsize directive, when its struct is new,
+// when the rule did not fire on the baseline, or when the layout changed
+// and wastes more bytes than before.
+func (b *Baseline) Filter(infos []structi.Info, findings []Finding, cfg Config) []Finding {
+	known := make(map[string]BaselineEntry)
+	var baselineInfos []structi.Info
+	for _, entry := range b.Structs {
+		known[entry.QualifiedName()] = entry
+		baselineInfos = append(baselineInfos, entry.Info)
+	}
+
+	knownRules := make(map[string]map[string]bool)
+	for _, f := range Check(baselineInfos, cfg) {
+		if knownRules[f.Struct] == nil {
+			knownRules[f.Struct] = make(map[string]bool)
+		}
+		knownRules[f.Struct][f.Rule] = true
+	}
+
+	current := make(map[string]structi.Info)
+	for _, info := range infos {
+		current[info.QualifiedName()] = info
+	}
+
+	var kept []Finding
+	for _, f := range findings {
+		entry, ok := known[f.Struct]
+		info, found := current[f.Struct]
+		switch {
+		case f.Rule == MaxSize.ID:
+			// limits set in the source are never baselined
+			kept = append(kept, f)
+		case !ok || !found:
+			kept = append(kept, f)
+		case info.LayoutHash() == entry.LayoutHash:
+			// unchanged since the baseline
+		case !knownRules[f.Struct][f.Rule], info.WastedBytes > entry.WastedBytes:
+			kept = append(kept, f)
+		}
+	}
+	return kept
+}
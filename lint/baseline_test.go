@@ -0,0 +1,120 @@
+package lint
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestBaselineFilter(t *testing.T) {
+	before := analyse(t, `package main
+
+type Kept struct {
+	A bool
+	B int64
+	C bool
+}
+
+type Shrunk struct {
+	A bool
+	B int64
+	C bool
+	D int64
+	E bool
+}
+
+type Grown struct {
+	A bool
+	B int64
+	C bool
+}
+`)
+	after := analyse(t, `package main
+
+type Kept struct {
+	A bool
+	B int64
+	C bool
+}
+
+type Shrunk struct {
+	A bool
+	B int64
+	C bool
+	D int64
+}
+
+type Grown struct {
+	A bool
+	B int64
+	C bool
+	D int32
+	E bool
+	F int64
+	G bool
+}
+
+type New struct {
+	A bool
+	B int64
+	C bool
+}
+`)
+
+	path := filepath.Join(t.TempDir(), "baseline.json")
+	if err := NewBaseline(before).Write(path); err != nil {
+		t.Fatalf("write error: %v", err)
+	}
+	baseline, err := ReadBaseline(path)
+	if err != nil {
+		t.Fatalf("read error: %v", err)
+	}
+
+	cfg := DefaultConfig()
+	cfg.MaxWastedBytes = 0
+
+	var got []string
+	for _, f := range baseline.Filter(after, Gate(after, cfg), cfg) {
+		got = append(got, f.Struct)
+	}
+	if want := []string{"Grown", "New"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got findings for %v, want %v", got, want)
+	}
+}
+
+func TestReadBaselineFromJSONOutput(t *testing.T) {
+	infos := analyse(t, `package main
+
+type Kept struct {
+	A bool
+	B int64
+	C bool
+}
+`)
+
+	// the json output format has no layout hash
+	data, err := json.Marshal(infos)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "structs.json")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	baseline, err := ReadBaseline(path)
+	if err != nil {
+		t.Fatalf("read error: %v", err)
+	}
+	if got, want := baseline.Structs[0].LayoutHash, infos[0].LayoutHash(); got != want {
+		t.Errorf("got layout hash %s, want %s", got, want)
+	}
+
+	cfg := DefaultConfig()
+	cfg.MaxWastedBytes = 0
+	if kept := baseline.Filter(infos, Gate(infos, cfg), cfg); len(kept) != 0 {
+		t.Errorf("unchanged struct reported: %+v", kept)
+	}
+}
@@ -0,0 +1,346 @@
+package lint
+
+import (
+	"fmt"
+	"go/types"
+	"strconv"
+	"strings"
+
+	"github.com/buarki/viztruct/structi"
+)
+
+type Level string
+
+const (
+	LevelError   Level = "error"
+	LevelWarning Level = "warning"
+	LevelNote    Level = "note"
+)
+
+// Rule describes a kind of finding.
+type Rule struct {
+	ID          string `json:"id"`
+	Description string `json:"description"`
+	Help        string `json:"help"`
+}
+
+var (
+	PaddingWaste = Rule{
+		ID:          "padding-waste",
+		Description: "Struct wastes space in padding that reordering its fields would recover.",
+		Help:        "Order the fields from the largest alignment to the smallest.",
+	}
+	SizeClassCrossing = Rule{
+		ID:          "size-class-crossing",
+		Description: "Reordering the fields would move heap allocations of the struct to a smaller runtime size class.",
+		Help:        "Heap objects are rounded up to a size class, shrinking below a class boundary saves memory on every allocation.",
+	}
+	FalseSharing = Rule{
+		ID:          "false-sharing",
+		Description: "Synchronization fields share a cache line, concurrent writers will invalidate each other's cache.",
+		Help:        "Pad the fields apart so each one sits on its own cache line.",
+	}
+	WasteThreshold = Rule{
+		ID:          "waste-threshold",
+		Description: "Struct wastes more space than the configured thresholds allow.",
+		Help:        "Reorder the fields as suggested or raise the thresholds.",
+	}
+
+	MaxSize = Rule{
+		ID:          "max-size",
+		Description: "Struct is bigger than the size its //viztruct:maxsize directive allows.",
+		Help:        "Shrink the struct or raise the limit of the directive.",
+	}
+
+	Rules = []Rule{PaddingWaste, SizeClassCrossing, FalseSharing, WasteThreshold, MaxSize}
+)
+
+const (
+	// IgnoreDirective excludes a struct from the findings, an optional
+	// reason="..." argument documents why.
+	IgnoreDirective = "ignore"
+	// MaxSizeDirective sets the maximum size in bytes of a struct on
+	// every architecture.
+	MaxSizeDirective = "maxsize"
+)
+
+// Config holds the thresholds deciding the level of findings and which
+// structs fail the gate.
+type Config struct {
+	// padding waste findings are errors from ErrorPercent wasted and
+	// warnings from WarningPercent, notes below
+	WarningPercent float64
+	ErrorPercent   float64
+	CacheLineSize  int64
+
+	// structs saving less than MinSavings bytes once reordered are not
+	// reported. With the default of 1, waste reordering cannot recover
+	// never fails the gate, 0 gates on it too
+	MinSavings int64
+	// the gate fails on structs wasting more than MaxWastedBytes or
+	// MaxWastedPercent, negative values disable the threshold
+	MaxWastedBytes   int64
+	MaxWastedPercent float64
+	// RequireSizeClassCrossing only fails the gate for structs whose
+	// reordering moves them to a smaller size class
+	RequireSizeClassCrossing bool
+}
+
+func DefaultConfig() Config {
+	return Config{
+		WarningPercent:   10,
+		ErrorPercent:     25,
+		CacheLineSize:    64,
+		MinSavings:       1,
+		MaxWastedBytes:   -1,
+		MaxWastedPercent: -1,
+	}
+}
+
+// GateEnabled reports whether any threshold of the gate is set.
+func (c Config) GateEnabled() bool {
+	return c.MaxWastedBytes >= 0 || c.MaxWastedPercent >= 0 || c.RequireSizeClassCrossing
+}
+
+// exceeds reports whether the struct fails the gate, and why.
+func (c Config) exceeds(info structi.Info) (string, bool) {
+	if !c.GateEnabled() || info.OriginalSize-info.OptimizedSize < c.MinSavings {
+		return "", false
+	}
+
+	var reasons []string
+	if c.MaxWastedBytes >= 0 && info.WastedBytes > c.MaxWastedBytes {
+		reasons = append(reasons, fmt.Sprintf("%d wasted bytes exceed the maximum of %d", info.WastedBytes, c.MaxWastedBytes))
+	}
+	if c.MaxWastedPercent >= 0 && info.WastedPercent > c.MaxWastedPercent {
+		reasons = append(reasons, fmt.Sprintf("%.2f%% wasted exceeds the maximum of %.2f%%", info.WastedPercent, c.MaxWastedPercent))
+	}
+	if c.MaxWastedBytes < 0 && c.MaxWastedPercent < 0 {
+		reasons = append(reasons, "reordering the fields changes its size class")
+	}
+	if len(reasons) == 0 {
+		return "", false
+	}
+
+	if c.RequireSizeClassCrossing && structi.SizeClass(info.OptimizedSize) >= structi.SizeClass(info.OriginalSize) {
+		return "", false
+	}
+	return strings.Join(reasons, ", "), true
+}
+
+// Finding is a problem found in the layout of a struct. Fix holds the
+// reordered declaration of the struct when one can be suggested.
+type Finding struct {
+	Rule      string `json:"rule"`
+	Level     Level  `json:"level"`
+	Struct    string `json:"struct"`
+	Message   string `json:"message"`
+	File      string `json:"file,omitempty"`
+	Line      int    `json:"line,omitempty"`
+	Column    int    `json:"column,omitempty"`
+	EndLine   int    `json:"end_line,omitempty"`
+	EndColumn int    `json:"end_column,omitempty"`
+	Fix       string `json:"fix,omitempty"`
+}
+
+// Check runs every rule against the structs.
+func Check(infos []structi.Info, cfg Config) []Finding {
+	var findings []Finding
+	for _, info := range infos {
+		findings = append(findings, checkStruct(info, cfg)...)
+	}
+	return findings
+}
+
+// Gate returns the findings failing the gate: structs over the thresholds
+// of cfg and over the limit of their maxsize directive.
+func Gate(infos []structi.Info, cfg Config) []Finding {
+	var failed []Finding
+	for _, f := range Check(infos, cfg) {
+		if f.Rule == WasteThreshold.ID || f.Rule == MaxSize.ID {
+			failed = append(failed, f)
+		}
+	}
+	return failed
+}
+
+// Ignored reports whether the struct is excluded from the findings by a
+// //viztruct:ignore directive, and the reason given if any.
+func Ignored(info structi.Info) (string, bool) {
+	d, ok := info.Directive(IgnoreDirective)
+	if !ok {
+		return "", false
+	}
+	reason, _ := d.Arg("reason")
+	return reason, true
+}
+
+// CheckMaxSize checks the maxsize directives of structs analysed for arch,
+// named in the messages to tell architectures apart.
+func CheckMaxSize(infos []structi.Info, arch string) []Finding {
+	var findings []Finding
+	for _, info := range infos {
+		if f, ok := checkMaxSize(info, arch); ok {
+			findings = append(findings, f)
+		}
+	}
+	return findings
+}
+
+func checkMaxSize(info structi.Info, arch string) (Finding, bool) {
+	d, ok := info.Directive(MaxSizeDirective)
+	if !ok {
+		return Finding{}, false
+	}
+
+	on := ""
+	if arch != "" {
+		on = " on " + arch
+	}
+
+	limit, err := strconv.ParseInt(strings.TrimSpace(d.Args), 10, 64)
+	if err != nil || limit < 0 {
+		return newFinding(info, MaxSize, LevelError, fmt.Sprintf("%s has an invalid //viztruct:maxsize directive %q", info.Name, d.Args)), true
+	}
+	if info.OriginalSize <= limit {
+		return Finding{}, false
+	}
+	return newFinding(info, MaxSize, LevelError, fmt.Sprintf("%s is %d bytes%s, over its maxsize of %d bytes", info.Name, info.OriginalSize, on, limit)), true
+}
+
+func newFinding(info structi.Info, rule Rule, level Level, message string) Finding {
+	return Finding{
+		Rule:      rule.ID,
+		Level:     level,
+		Struct:    info.QualifiedName(),
+		Message:   message,
+		File:      info.File,
+		Line:      info.Line,
+		Column:    info.Column,
+		EndLine:   info.EndLine,
+		EndColumn: info.EndColumn,
+	}
+}
+
+func checkStruct(info structi.Info, cfg Config) []Finding {
+	var findings []Finding
+	if f, ok := checkMaxSize(info, ""); ok {
+		findings = append(findings, f)
+	}
+
+	// ignored structs keep the limit they explicitly ask for
+	if _, ignored := Ignored(info); ignored {
+		return findings
+	}
+
+	saved := info.OriginalSize - info.OptimizedSize
+	if saved > 0 && saved >= cfg.MinSavings {
+		level := LevelNote
+		switch {
+		case info.WastedPercent >= cfg.ErrorPercent:
+			level = LevelError
+		case info.WastedPercent >= cfg.WarningPercent:
+			level = LevelWarning
+		}
+		f := newFinding(info, PaddingWaste, level, fmt.Sprintf("%s wastes %d of its %d bytes (%.2f%%), reordering the fields saves %d bytes",
+			info.Name, info.WastedBytes, info.OriginalSize, info.WastedPercent, saved))
+		f.Fix = suggestedDeclaration(info)
+		findings = append(findings, f)
+
+		if before, after := structi.SizeClass(info.OriginalSize), structi.SizeClass(info.OptimizedSize); after < before {
+			f := newFinding(info, SizeClassCrossing, LevelWarning, fmt.Sprintf("%s is allocated in the %d bytes size class, reordering the fields moves it to the %d bytes one",
+				info.Name, before, after))
+			f.Fix = suggestedDeclaration(info)
+			findings = append(findings, f)
+		}
+	}
+
+	if reason, ok := cfg.exceeds(info); ok {
+		f := newFinding(info, WasteThreshold, LevelError, fmt.Sprintf("%s: %s", info.Name, reason))
+		f.Fix = suggestedDeclaration(info)
+		findings = append(findings, f)
+	}
+
+	if cfg.CacheLineSize > 0 {
+		var syncFields []structi.Field
+		for _, field := range info.Fields {
+			if !field.IsPadding && isSyncType(field.TypeName) {
+				syncFields = append(syncFields, field)
+			}
+		}
+		for i := 1; i < len(syncFields); i++ {
+			prev, cur := syncFields[i-1], syncFields[i]
+			// the last byte of the previous field and the first of this one
+			if (prev.Offset+prev.Size-1)/cfg.CacheLineSize == cur.Offset/cfg.CacheLineSize {
+				findings = append(findings, newFinding(info, FalseSharing, LevelWarning, fmt.Sprintf("%s.%s (%s) and %s.%s (%s) share a %d bytes cache line",
+					info.Name, prev.Name, prev.TypeName, info.Name, cur.Name, cur.TypeName, cfg.CacheLineSize)))
+			}
+		}
+	}
+
+	return findings
+}
+
+func isSyncType(typeName string) bool {
+	typeName = strings.TrimPrefix(typeName, "*")
+	return strings.HasPrefix(typeName, "sync.") || strings.HasPrefix(typeName, "sync/atomic.") || strings.HasPrefix(typeName, "atomic.")
+}
+
+// suggestedDeclaration writes the type spec of the struct with its fields
+// in the optimized order and their tags. Type names are only known in
+// source form for structs of loaded packages, and no declaration is
+// suggested when comments in the struct body would be lost.
+func suggestedDeclaration(info structi.Info) string {
+	if info.Package == "" || info.EndLine == 0 || info.Type == nil || info.BodyComments {
+		return ""
+	}
+
+	// fields are told apart by name and type, blank ones share a name
+	fields := make(map[string]int)
+	for i := 0; i < info.Type.NumFields(); i++ {
+		v := info.Type.Field(i)
+		fields[v.Name()+" "+v.Type().String()] = i
+	}
+	// types of the struct package are written unqualified, the others
+	// with the name of their package
+	qualifier := func(pkg *types.Package) string {
+		if pkg.Path() == info.Package {
+			return ""
+		}
+		return pkg.Name()
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s struct {\n", info.Name)
+	for _, f := range info.OptimizedFields {
+		if f.IsPadding {
+			continue
+		}
+		i, ok := fields[f.Name+" "+f.TypeName]
+		if !ok {
+			return ""
+		}
+		v := info.Type.Field(i)
+
+		typ := types.TypeString(v.Type(), qualifier)
+		if v.Embedded() {
+			fmt.Fprintf(&sb, "\t%s", typ)
+		} else {
+			fmt.Fprintf(&sb, "\t%s %s", f.Name, typ)
+		}
+		if tag := info.Type.Tag(i); tag != "" {
+			fmt.Fprintf(&sb, " %s", quoteTag(tag))
+		}
+		sb.WriteString("\n")
+	}
+	sb.WriteString("}")
+	return sb.String()
+}
+
+// quoteTag writes a struct tag as a raw string unless it holds a backquote.
+func quoteTag(tag string) string {
+	if strings.Contains(tag, "`") {
+		return strconv.Quote(tag)
+	}
+	return "`" + tag + "`"
+}
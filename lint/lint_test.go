@@ -0,0 +1,321 @@
+package lint
+
+import (
+	"encoding/json"
+	"go/types"
+	"reflect"
+	"testing"
+
+	"github.com/buarki/viztruct/structi"
+)
+
+func analyse(t *testing.T, input string) []structi.Info {
+	t.Helper()
+	infos, err := structi.AnalyseStructs(input)
+	if err != nil {
+		t.Fatalf("analyse error: %v", err)
+	}
+	return infos
+}
+
+func TestCheck(t *testing.T) {
+	infos := analyse(t, `package main
+
+type Wasteful struct {
+	A bool
+	B int64
+	C bool
+	D int64
+	E bool
+	F int64
+	G bool
+}
+
+type Slight struct {
+	A bool
+	B int64
+	C bool
+	D int32
+	E [120]byte
+}
+
+type Packed struct {
+	B int64
+	A bool
+}
+`)
+
+	var got []string
+	for _, f := range Check(infos, DefaultConfig()) {
+		got = append(got, f.Struct+" "+f.Rule+" "+string(f.Level))
+	}
+	want := []string{
+		"Wasteful padding-waste error",
+		"Wasteful size-class-crossing warning",
+		"Slight padding-waste note",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got findings %v, want %v", got, want)
+	}
+}
+
+func TestGate(t *testing.T) {
+	infos := analyse(t, `package main
+
+// 48 bytes, 21 wasted, 32 once reordered
+type Wasteful struct {
+	A bool
+	B int64
+	C bool
+	D int64
+	E bool
+	F int64
+}
+
+// 64 bytes, 14 wasted, 56 once reordered: same size class
+type Large struct {
+	A bool
+	B int64
+	C bool
+	X [40]byte
+}
+
+// 16 bytes, 7 wasted that reordering cannot recover
+type Unfixable struct {
+	B int64
+	A bool
+}
+`)
+
+	tests := []struct {
+		name string
+		cfg  func(*Config)
+		want []string
+	}{
+		{
+			name: "disabled by default",
+			cfg:  func(*Config) {},
+		},
+		{
+			name: "max wasted bytes",
+			cfg:  func(c *Config) { c.MaxWastedBytes = 0 },
+			want: []string{"Wasteful", "Large"},
+		},
+		{
+			name: "max wasted percent",
+			cfg:  func(c *Config) { c.MaxWastedPercent = 40 },
+			want: []string{"Wasteful"},
+		},
+		{
+			name: "min savings",
+			cfg:  func(c *Config) { c.MaxWastedBytes = 0; c.MinSavings = 16 },
+			want: []string{"Wasteful"},
+		},
+		{
+			name: "unrecoverable waste",
+			cfg:  func(c *Config) { c.MaxWastedBytes = 0; c.MinSavings = 0 },
+			want: []string{"Wasteful", "Large", "Unfixable"},
+		},
+		{
+			name: "size class crossing required",
+			cfg:  func(c *Config) { c.MaxWastedBytes = 0; c.RequireSizeClassCrossing = true },
+			want: []string{"Wasteful"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			tt.cfg(&cfg)
+
+			var got []string
+			for _, f := range Gate(infos, cfg) {
+				if f.Level != LevelError {
+					t.Errorf("gate finding %+v is not an error", f)
+				}
+				got = append(got, f.Struct)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckFalseSharing(t *testing.T) {
+	info := structi.Info{
+		Name:    "Stats",
+		Package: "example.com/stats",
+		Fields: []structi.Field{
+			{Name: "mu", TypeName: "sync.Mutex", Offset: 0, Size: 8, Align: 4},
+			{Name: "hits", TypeName: "sync/atomic.Int64", Offset: 8, Size: 8, Align: 8},
+			{Name: "pad", TypeName: "[64]byte", Offset: 16, Size: 64, Align: 1},
+			{Name: "misses", TypeName: "sync/atomic.Int64", Offset: 80, Size: 8, Align: 8},
+		},
+	}
+
+	findings := Check([]structi.Info{info}, DefaultConfig())
+	if len(findings) != 1 {
+		t.Fatalf("expected 1 finding, got %+v", findings)
+	}
+	want := "Stats.mu (sync.Mutex) and Stats.hits (sync/atomic.Int64) share a 64 bytes cache line"
+	if findings[0].Rule != FalseSharing.ID || findings[0].Message != want {
+		t.Errorf("got %+v", findings[0])
+	}
+}
+
+func TestSuggestedDeclaration(t *testing.T) {
+	session := types.NewPackage("example.com/app/session", "session")
+	user := types.NewPackage("example.com/app/user", "user")
+	yaml := types.NewPackage("gopkg.in/yaml.v3", "yaml")
+	sync := types.NewPackage("sync", "sync")
+	named := func(pkg *types.Package, name string) types.Type {
+		return types.NewNamed(types.NewTypeName(0, pkg, name, nil), types.NewStruct(nil, nil), nil)
+	}
+
+	vars := []*types.Var{
+		types.NewField(0, session, "Users", types.NewSlice(named(user, "User")), false),
+		types.NewField(0, session, "Tok", named(session, "Token"), false),
+		types.NewField(0, session, "Conf", types.NewPointer(named(yaml, "Node")), false),
+		types.NewField(0, session, "Mutex", named(sync, "Mutex"), true),
+	}
+
+	info := structi.Info{
+		Name:    "Session",
+		Package: "example.com/app/session",
+		EndLine: 10,
+		Type:    types.NewStruct(vars, []string{`json:"users"`, "", `yaml:"conf,omitempty"`, ""}),
+		OptimizedFields: []structi.Field{
+			{Name: "Users", TypeName: vars[0].Type().String()},
+			{Name: "Tok", TypeName: vars[1].Type().String()},
+			{Name: "Conf", TypeName: vars[2].Type().String()},
+			{Name: "Mutex", TypeName: vars[3].Type().String()},
+			{Name: "padding", IsPadding: true},
+		},
+	}
+
+	want := "Session struct {\n\tUsers []user.User `json:\"users\"`\n\tTok Token\n\tConf *yaml.Node `yaml:\"conf,omitempty\"`\n\tsync.Mutex\n}"
+	if got := suggestedDeclaration(info); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+
+	// comments in the body cannot be carried over
+	info.BodyComments = true
+	if got := suggestedDeclaration(info); got != "" {
+		t.Errorf("expected no declaration for a commented struct, got\n%s", got)
+	}
+}
+
+func TestSARIF(t *testing.T) {
+	findings := []Finding{{
+		Rule:    PaddingWaste.ID,
+		Level:   LevelWarning,
+		Struct:  "example.com/app.Session",
+		Message: "Session wastes 8 bytes",
+		File:    "/src/app/session.go",
+		Line:    3,
+		Column:  6,
+		EndLine: 8,
+		Fix:     "Session struct {\n}",
+	}}
+
+	data, err := SARIF(findings, "v1.0.0", "/src")
+	if err != nil {
+		t.Fatalf("sarif error: %v", err)
+	}
+
+	var log sarifLog
+	if err := json.Unmarshal(data, &log); err != nil {
+		t.Fatalf("invalid json: %v", err)
+	}
+	if log.Version != "2.1.0" || len(log.Runs) != 1 {
+		t.Fatalf("unexpected log %+v", log)
+	}
+
+	run := log.Runs[0]
+	if len(run.Tool.Driver.Rules) != len(Rules) || len(run.Results) != 1 {
+		t.Fatalf("unexpected run %+v", run)
+	}
+
+	result := run.Results[0]
+	location := result.Locations[0].PhysicalLocation
+	if location.ArtifactLocation.URI != "app/session.go" || location.ArtifactLocation.URIBaseID != srcRoot {
+		t.Errorf("unexpected artifact location %+v", location.ArtifactLocation)
+	}
+	if location.Region.StartLine != 3 || location.Region.EndLine != 8 {
+		t.Errorf("unexpected region %+v", location.Region)
+	}
+	if len(result.Fixes) != 1 || result.Fixes[0].ArtifactChanges[0].Replacements[0].InsertedContent.Text != "Session struct {\n}" {
+		t.Errorf("unexpected fixes %+v", result.Fixes)
+	}
+}
+
+func TestSARIFInlineInput(t *testing.T) {
+	findings := []Finding{{Rule: PaddingWaste.ID, Level: LevelWarning, Struct: "Session", Message: "Session wastes 8 bytes"}}
+
+	data, err := SARIF(findings, "v1.0.0", "/src")
+	if err != nil {
+		t.Fatalf("sarif error: %v", err)
+	}
+
+	var log sarifLog
+	if err := json.Unmarshal(data, &log); err != nil {
+		t.Fatalf("invalid json: %v", err)
+	}
+	result := log.Runs[0].Results[0]
+	if len(result.Locations) != 1 {
+		t.Fatalf("expected a fallback location, got %+v", result.Locations)
+	}
+	location := result.Locations[0].PhysicalLocation
+	if location.ArtifactLocation.URI != inlineArtifact || location.Region.StartLine != 1 {
+		t.Errorf("unexpected location %+v", location)
+	}
+	if len(result.Fixes) != 0 {
+		t.Errorf("unexpected fixes %+v", result.Fixes)
+	}
+}
+
+func TestDirectives(t *testing.T) {
+	infos := analyse(t, `package main
+
+//viztruct:ignore reason="wire format"
+type Wire struct {
+	A bool
+	B int64
+	C bool
+}
+
+//viztruct:ignore
+//viztruct:maxsize 16
+type Limited struct {
+	A bool
+	B int64
+	C bool
+}
+
+//viztruct:maxsize lots
+type Invalid struct {
+	A int64
+}
+`)
+
+	if reason, ok := Ignored(infos[0]); !ok || reason != "wire format" {
+		t.Errorf("got ignore reason %q, %v", reason, ok)
+	}
+
+	var got []string
+	for _, f := range Gate(infos, DefaultConfig()) {
+		got = append(got, f.Message)
+	}
+	want := []string{
+		"Limited is 24 bytes, over its maxsize of 16 bytes",
+		`Invalid has an invalid //viztruct:maxsize directive "lots"`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got\n%v\nwant\n%v", got, want)
+	}
+
+	if findings := CheckMaxSize(infos[1:2], "386"); len(findings) != 1 || findings[0].Message != "Limited is 24 bytes on 386, over its maxsize of 16 bytes" {
+		t.Errorf("unexpected findings %+v", findings)
+	}
+}
@@ -0,0 +1,170 @@
+package lint
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	// srcRoot is the base of relative artifact locations, code scanning
+	// services resolve it to the root of the checked out repository
+	srcRoot = "%SRCROOT%"
+	// inlineArtifact stands for structs given inline or on stdin, code
+	// scanning services drop the results that have no location
+	inlineArtifact = "stdin"
+)
+
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version,omitempty"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID                   string       `json:"id"`
+	ShortDescription     sarifMessage `json:"shortDescription"`
+	Help                 sarifMessage `json:"help"`
+	DefaultConfiguration struct {
+		Level Level `json:"level"`
+	} `json:"defaultConfiguration"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	RuleIndex int             `json:"ruleIndex"`
+	Level     Level           `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+	Fixes     []sarifFix      `json:"fixes,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId,omitempty"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+	EndLine     int `json:"endLine,omitempty"`
+	EndColumn   int `json:"endColumn,omitempty"`
+}
+
+type sarifFix struct {
+	Description     sarifMessage          `json:"description"`
+	ArtifactChanges []sarifArtifactChange `json:"artifactChanges"`
+}
+
+type sarifArtifactChange struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Replacements     []sarifReplacement    `json:"replacements"`
+}
+
+type sarifReplacement struct {
+	DeletedRegion   sarifRegion   `json:"deletedRegion"`
+	InsertedContent *sarifMessage `json:"insertedContent,omitempty"`
+}
+
+// SARIF encodes the findings as a SARIF 2.1.0 log. Files under root are
+// referenced relative to it so code scanning services can map them to the
+// repository.
+func SARIF(findings []Finding, version, root string) ([]byte, error) {
+	driver := sarifDriver{
+		Name:           "viztruct",
+		Version:        version,
+		InformationURI: "https://github.com/buarki/viztruct",
+	}
+	ruleIndex := make(map[string]int)
+	for i, r := range Rules {
+		rule := sarifRule{ID: r.ID, ShortDescription: sarifMessage{r.Description}, Help: sarifMessage{r.Help}}
+		rule.DefaultConfiguration.Level = LevelWarning
+		driver.Rules = append(driver.Rules, rule)
+		ruleIndex[r.ID] = i
+	}
+
+	results := []sarifResult{}
+	for _, f := range findings {
+		result := sarifResult{
+			RuleID:    f.Rule,
+			RuleIndex: ruleIndex[f.Rule],
+			Level:     f.Level,
+			Message:   sarifMessage{f.Message},
+		}
+
+		artifact := sarifArtifactLocation{URI: inlineArtifact}
+		if f.File != "" {
+			artifact = artifactLocation(f.File, root)
+		}
+		region := sarifRegion{StartLine: max(f.Line, 1), StartColumn: f.Column, EndLine: f.EndLine, EndColumn: f.EndColumn}
+		result.Locations = []sarifLocation{{sarifPhysicalLocation{ArtifactLocation: artifact, Region: region}}}
+
+		if f.File != "" && f.Line > 0 && f.Fix != "" && f.EndLine > 0 {
+			result.Fixes = []sarifFix{{
+				Description: sarifMessage{"Reorder the fields by decreasing alignment"},
+				ArtifactChanges: []sarifArtifactChange{{
+					ArtifactLocation: artifact,
+					Replacements:     []sarifReplacement{{DeletedRegion: region, InsertedContent: &sarifMessage{f.Fix}}},
+				}},
+			}}
+		}
+
+		results = append(results, result)
+	}
+
+	log := sarifLog{
+		Version: sarifVersion,
+		Schema:  sarifSchema,
+		Runs:    []sarifRun{{Tool: sarifTool{Driver: driver}, Results: results}},
+	}
+	data, err := json.MarshalIndent(log, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("error encoding sarif: %v", err)
+	}
+	return data, nil
+}
+
+func artifactLocation(file, root string) sarifArtifactLocation {
+	if root != "" {
+		if rel, err := filepath.Rel(root, file); err == nil && !strings.HasPrefix(rel, "..") {
+			return sarifArtifactLocation{URI: filepath.ToSlash(rel), URIBaseID: srcRoot}
+		}
+	}
+	if filepath.IsAbs(file) {
+		return sarifArtifactLocation{URI: (&url.URL{Scheme: "file", Path: filepath.ToSlash(file)}).String()}
+	}
+	return sarifArtifactLocation{URI: filepath.ToSlash(file)}
+}
@@ -0,0 +1,205 @@
+package lock
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/buarki/viztruct/structi"
+)
+
+const (
+	Version = 1
+
+	// StableDirective marks a struct whose layout must be locked.
+	StableDirective = "stable"
+)
+
+// File is the content of a layout lock file: the layout of each locked
+// struct, by qualified name, on each configured architecture.
+type File struct {
+	Version int                          `json:"version"`
+	Archs   []string                     `json:"archs"`
+	Structs map[string]map[string]Layout `json:"structs"`
+}
+
+type Layout struct {
+	Size   int64         `json:"size"`
+	Align  int64         `json:"align"`
+	Fields []FieldLayout `json:"fields"`
+}
+
+type FieldLayout struct {
+	Name   string `json:"name"`
+	Type   string `json:"type"`
+	Offset int64  `json:"offset"`
+	Size   int64  `json:"size"`
+}
+
+// Mismatch is a difference between a locked layout and the current one.
+type Mismatch struct {
+	Struct  string `json:"struct"`
+	Arch    string `json:"arch"`
+	Message string `json:"message"`
+}
+
+func LayoutOf(info structi.Info) Layout {
+	layout := Layout{Size: info.OriginalSize, Align: info.Align()}
+	for _, f := range info.Fields {
+		if f.IsPadding {
+			continue
+		}
+		layout.Fields = append(layout.Fields, FieldLayout{Name: f.Name, Type: f.TypeName, Offset: f.Offset, Size: f.Size})
+	}
+	return layout
+}
+
+// Select returns the structs to lock: the ones marked with
+// //viztruct:stable and the ones named, by plain or qualified name.
+func Select(infos []structi.Info, names []string) []structi.Info {
+	wanted := make(map[string]bool)
+	for _, n := range names {
+		wanted[n] = true
+	}
+
+	var selected []structi.Info
+	for _, info := range infos {
+		_, stable := info.Directive(StableDirective)
+		if stable || wanted[info.Name] || wanted[info.QualifiedName()] {
+			selected = append(selected, info)
+		}
+	}
+	return selected
+}
+
+// Record builds a lock file from the selected structs of each architecture.
+func Record(byArch map[string][]structi.Info) *File {
+	f := &File{Version: Version, Structs: make(map[string]map[string]Layout)}
+	for arch, infos := range byArch {
+		f.Archs = append(f.Archs, arch)
+		for _, info := range infos {
+			name := info.QualifiedName()
+			if f.Structs[name] == nil {
+				f.Structs[name] = make(map[string]Layout)
+			}
+			f.Structs[name][arch] = LayoutOf(info)
+		}
+	}
+	sort.Strings(f.Archs)
+	return f
+}
+
+// Check compares the locked layouts against the current structs of each
+// architecture. Structs marked stable that are missing from the lock file
+// are reported too so new ones cannot slip in unlocked.
+func Check(f *File, byArch map[string][]structi.Info) []Mismatch {
+	var mismatches []Mismatch
+
+	names := make([]string, 0, len(f.Structs))
+	for name := range f.Structs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, arch := range f.Archs {
+		current := make(map[string]structi.Info)
+		for _, info := range byArch[arch] {
+			current[info.QualifiedName()] = info
+		}
+
+		for _, name := range names {
+			locked, ok := f.Structs[name][arch]
+			if !ok {
+				continue
+			}
+			info, ok := current[name]
+			if !ok {
+				mismatches = append(mismatches, Mismatch{Struct: name, Arch: arch, Message: "struct no longer exists"})
+				continue
+			}
+			for _, msg := range compare(locked, LayoutOf(info)) {
+				mismatches = append(mismatches, Mismatch{Struct: name, Arch: arch, Message: msg})
+			}
+		}
+
+		for _, info := range byArch[arch] {
+			if _, stable := info.Directive(StableDirective); !stable {
+				continue
+			}
+			if _, ok := f.Structs[info.QualifiedName()]; !ok {
+				mismatches = append(mismatches, Mismatch{
+					Struct:  info.QualifiedName(),
+					Arch:    arch,
+					Message: "marked //viztruct:stable but missing from the lock file",
+				})
+			}
+		}
+	}
+
+	return mismatches
+}
+
+func compare(locked, current Layout) []string {
+	var diffs []string
+	if locked.Size != current.Size {
+		diffs = append(diffs, fmt.Sprintf("size changed from %d to %d bytes", locked.Size, current.Size))
+	}
+	if locked.Align != current.Align {
+		diffs = append(diffs, fmt.Sprintf("alignment changed from %d to %d", locked.Align, current.Align))
+	}
+
+	currentFields := make(map[string]FieldLayout)
+	for _, f := range current.Fields {
+		currentFields[f.Name] = f
+	}
+	lockedFields := make(map[string]bool)
+
+	for _, lf := range locked.Fields {
+		lockedFields[lf.Name] = true
+		cf, ok := currentFields[lf.Name]
+		switch {
+		case !ok:
+			diffs = append(diffs, fmt.Sprintf("field %s removed (was at offset %d)", lf.Name, lf.Offset))
+		case cf.Offset != lf.Offset:
+			diffs = append(diffs, fmt.Sprintf("field %s moved from offset %d to %d", lf.Name, lf.Offset, cf.Offset))
+		case cf.Size != lf.Size || cf.Type != lf.Type:
+			diffs = append(diffs, fmt.Sprintf("field %s changed from %s (%d bytes) to %s (%d bytes)", lf.Name, lf.Type, lf.Size, cf.Type, cf.Size))
+		}
+	}
+
+	for _, cf := range current.Fields {
+		if !lockedFields[cf.Name] {
+			diffs = append(diffs, fmt.Sprintf("field %s (%s) added at offset %d", cf.Name, cf.Type, cf.Offset))
+		}
+	}
+
+	return diffs
+}
+
+func Read(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading lock file: %v", err)
+	}
+
+	var f File
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("error decoding lock file: %v", err)
+	}
+	if f.Version != Version {
+		return nil, fmt.Errorf("unsupported lock file version %d", f.Version)
+	}
+	return &f, nil
+}
+
+func (f *File) Write(path string) error {
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding lock file: %v", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("error writing lock file: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package lock
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/buarki/viztruct/structi"
+)
+
+func analyse(t *testing.T, input string) []structi.Info {
+	t.Helper()
+	infos, err := structi.AnalyseStructs(input)
+	if err != nil {
+		t.Fatalf("analyse error: %v", err)
+	}
+	return infos
+}
+
+func TestSelect(t *testing.T) {
+	infos := analyse(t, `package main
+
+//viztruct:stable
+type Header struct {
+	Magic uint32
+}
+
+type Named struct {
+	A int
+}
+
+type Other struct {
+	B int
+}
+`)
+
+	var got []string
+	for _, info := range Select(infos, []string{"Named"}) {
+		got = append(got, info.Name)
+	}
+	if want := []string{"Header", "Named"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("selected %v, want %v", got, want)
+	}
+}
+
+func TestRecordAndCheck(t *testing.T) {
+	locked := analyse(t, `package main
+
+//viztruct:stable
+type Header struct {
+	Magic   uint32
+	Version uint16
+	Flags   uint16
+}
+`)
+
+	path := filepath.Join(t.TempDir(), "viztruct.lock")
+	if err := Record(map[string][]structi.Info{"amd64": locked}).Write(path); err != nil {
+		t.Fatalf("write error: %v", err)
+	}
+	f, err := Read(path)
+	if err != nil {
+		t.Fatalf("read error: %v", err)
+	}
+
+	if mismatches := Check(f, map[string][]structi.Info{"amd64": locked}); len(mismatches) != 0 {
+		t.Errorf("unchanged layout reported %v", mismatches)
+	}
+
+	changed := analyse(t, `package main
+
+//viztruct:stable
+type Header struct {
+	Magic   uint32
+	Flags   uint16
+	Version uint32
+}
+
+//viztruct:stable
+type Footer struct {
+	CRC uint32
+}
+`)
+
+	var got []string
+	for _, m := range Check(f, map[string][]structi.Info{"amd64": changed}) {
+		got = append(got, m.Struct+": "+m.Message)
+	}
+	want := []string{
+		"Header: size changed from 8 to 12 bytes",
+		"Header: field Version moved from offset 4 to 8",
+		"Header: field Flags moved from offset 6 to 4",
+		"Footer: marked //viztruct:stable but missing from the lock file",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got mismatches\n%v\nwant\n%v", got, want)
+	}
+}
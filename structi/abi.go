@@ -0,0 +1,300 @@
+package structi
+
+import (
+	"fmt"
+	"go/ast"
+	"go/types"
+	"sort"
+)
+
+// ABIArchs lists the architectures register assignment can be computed for.
+var ABIArchs = []string{"amd64", "arm64"}
+
+type abiRegisters struct {
+	ints   []string
+	floats []string
+}
+
+// registers available to ABIInternal for arguments and results
+var abiRegisterSets = map[string]abiRegisters{
+	"amd64": {
+		ints:   []string{"RAX", "RBX", "RCX", "RDI", "RSI", "R8", "R9", "R10", "R11"},
+		floats: []string{"X0", "X1", "X2", "X3", "X4", "X5", "X6", "X7", "X8", "X9", "X10", "X11", "X12", "X13", "X14"},
+	},
+	"arm64": {
+		ints:   []string{"R0", "R1", "R2", "R3", "R4", "R5", "R6", "R7", "R8", "R9", "R10", "R11", "R12", "R13", "R14", "R15"},
+		floats: []string{"F0", "F1", "F2", "F3", "F4", "F5", "F6", "F7", "F8", "F9", "F10", "F11", "F12", "F13", "F14", "F15"},
+	},
+}
+
+// RegisterAssignment describes how a value is passed under ABIInternal.
+type RegisterAssignment struct {
+	Arch        string   `json:"arch"`
+	InRegisters bool     `json:"in_registers"`
+	Ints        []string `json:"int_registers,omitempty"`
+	Floats      []string `json:"float_registers,omitempty"`
+	Reason      string   `json:"reason,omitempty"`
+}
+
+// abiAssigner follows the register assignment algorithm described in
+// cmd/compile/abi-internal.md. Registers are shared by all the arguments
+// of a call, so the same assigner is used for a whole signature.
+type abiAssigner struct {
+	regs   abiRegisters
+	ints   []string
+	floats []string
+}
+
+func newABIAssigner(arch string) (*abiAssigner, error) {
+	regs, ok := abiRegisterSets[arch]
+	if !ok {
+		return nil, fmt.Errorf("register assignment not supported on %s", arch)
+	}
+	return &abiAssigner{regs: regs}, nil
+}
+
+// assign register-assigns a value, on failure the registers are left
+// untouched and the reason the value goes to the stack is returned.
+func (a *abiAssigner) assign(t types.Type, label string) (string, bool) {
+	ints, floats := len(a.ints), len(a.floats)
+	if reason := a.assignRec(t, label); reason != "" {
+		a.ints, a.floats = a.ints[:ints], a.floats[:floats]
+		return reason, false
+	}
+	return "", true
+}
+
+func (a *abiAssigner) intReg(label string) string {
+	if len(a.ints) == len(a.regs.ints) {
+		return fmt.Sprintf("out of integer registers at %s", label)
+	}
+	a.ints = append(a.ints, a.regs.ints[len(a.ints)]+"="+label)
+	return ""
+}
+
+func (a *abiAssigner) floatReg(label string) string {
+	if len(a.floats) == len(a.regs.floats) {
+		return fmt.Sprintf("out of floating-point registers at %s", label)
+	}
+	a.floats = append(a.floats, a.regs.floats[len(a.floats)]+"="+label)
+	return ""
+}
+
+func (a *abiAssigner) parts(label string, parts ...string) []string {
+	labels := make([]string, len(parts))
+	for i, p := range parts {
+		labels[i] = label + "." + p
+	}
+	return labels
+}
+
+func (a *abiAssigner) assignRec(t types.Type, label string) string {
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		switch {
+		case u.Info()&types.IsComplex != 0:
+			for _, l := range a.parts(label, "real", "imag") {
+				if reason := a.floatReg(l); reason != "" {
+					return reason
+				}
+			}
+			return ""
+		case u.Info()&types.IsFloat != 0:
+			return a.floatReg(label)
+		case u.Kind() == types.String:
+			for _, l := range a.parts(label, "ptr", "len") {
+				if reason := a.intReg(l); reason != "" {
+					return reason
+				}
+			}
+			return ""
+		default:
+			return a.intReg(label)
+		}
+	case *types.Pointer, *types.Map, *types.Chan, *types.Signature:
+		return a.intReg(label)
+	case *types.Interface:
+		for _, l := range a.parts(label, "type", "data") {
+			if reason := a.intReg(l); reason != "" {
+				return reason
+			}
+		}
+		return ""
+	case *types.Slice:
+		for _, l := range a.parts(label, "ptr", "len", "cap") {
+			if reason := a.intReg(l); reason != "" {
+				return reason
+			}
+		}
+		return ""
+	case *types.Struct:
+		for i := 0; i < u.NumFields(); i++ {
+			if reason := a.assignRec(u.Field(i).Type(), label+"."+u.Field(i).Name()); reason != "" {
+				return reason
+			}
+		}
+		return ""
+	case *types.Array:
+		switch u.Len() {
+		case 0:
+			return ""
+		case 1:
+			return a.assignRec(u.Elem(), label+"[0]")
+		default:
+			return fmt.Sprintf("%s is an array of %d elements", label, u.Len())
+		}
+	}
+	return fmt.Sprintf("%s has a type that cannot be register-assigned", label)
+}
+
+// AssignRegisters computes how a struct passed by value as the first
+// argument of a function gets assigned to registers on the given
+// architecture, or why it spills to the stack.
+func (i Info) AssignRegisters(arch string) (RegisterAssignment, error) {
+	if i.Type == nil {
+		return RegisterAssignment{}, fmt.Errorf("no type information for %s", i.Name)
+	}
+
+	a, err := newABIAssigner(arch)
+	if err != nil {
+		return RegisterAssignment{}, err
+	}
+
+	// labels are field paths, drop the leading struct name
+	var assignment RegisterAssignment
+	for f := 0; f < i.Type.NumFields() && assignment.Reason == ""; f++ {
+		field := i.Type.Field(f)
+		if reason := a.assignRec(field.Type(), field.Name()); reason != "" {
+			assignment.Reason = reason
+		}
+	}
+
+	assignment.Arch = arch
+	if assignment.Reason == "" {
+		assignment.InRegisters = true
+		assignment.Ints, assignment.Floats = a.ints, a.floats
+	}
+	return assignment, nil
+}
+
+// SpilledParam is a struct passed or returned by value that does not fit
+// in the registers left for it and goes through the stack.
+type SpilledParam struct {
+	Func   string   `json:"func"`
+	Param  string   `json:"param"`
+	Type   string   `json:"type"`
+	Size   int64    `json:"size"`
+	Result bool     `json:"result"`
+	Archs  []string `json:"archs"`
+	Reason string   `json:"reason"`
+	File   string   `json:"file"`
+	Line   int      `json:"line"`
+}
+
+// FindSpilledParams scans the function signatures of the packages for
+// struct parameters and results that ABIInternal passes on the stack.
+func FindSpilledParams(pkgs []*Package) []SpilledParam {
+	var spilled []SpilledParam
+	for _, pkg := range pkgs {
+		for _, file := range pkg.Files {
+			for _, decl := range file.Decls {
+				fn, ok := decl.(*ast.FuncDecl)
+				if !ok {
+					continue
+				}
+				obj, ok := pkg.TypesInfo.Defs[fn.Name].(*types.Func)
+				if !ok {
+					continue
+				}
+				spilled = append(spilled, spilledInSignature(pkg, fn, obj.Type().(*types.Signature))...)
+			}
+		}
+	}
+	return spilled
+}
+
+func spilledInSignature(pkg *Package, fn *ast.FuncDecl, sig *types.Signature) []SpilledParam {
+	type key struct {
+		param  string
+		result bool
+	}
+	found := make(map[key]*SpilledParam)
+	var order []key
+
+	name := fn.Name.Name
+	if sig.Recv() != nil {
+		name = types.TypeString(sig.Recv().Type(), types.RelativeTo(pkg.Types)) + "." + name
+	}
+
+	for _, arch := range ABIArchs {
+		// arguments and results are assigned independently
+		groups := []struct {
+			vars   []*types.Var
+			result bool
+		}{
+			{vars: signatureParams(sig), result: false},
+			{vars: tupleVars(sig.Results()), result: true},
+		}
+
+		for _, g := range groups {
+			a, _ := newABIAssigner(arch)
+			for idx, v := range g.vars {
+				label := v.Name()
+				if label == "" || label == "_" {
+					label = fmt.Sprintf("#%d", idx)
+				}
+
+				reason, ok := a.assign(v.Type(), label)
+				if ok {
+					continue
+				}
+				if _, isStruct := v.Type().Underlying().(*types.Struct); !isStruct {
+					continue
+				}
+
+				k := key{param: label, result: g.result}
+				if p, ok := found[k]; ok {
+					p.Archs = append(p.Archs, arch)
+					continue
+				}
+
+				pos := pkg.Fset.Position(v.Pos())
+				found[k] = &SpilledParam{
+					Func:   name,
+					Param:  label,
+					Type:   types.TypeString(v.Type(), types.RelativeTo(pkg.Types)),
+					Size:   pkg.Sizes.Sizeof(v.Type()),
+					Result: g.result,
+					Archs:  []string{arch},
+					Reason: reason,
+					File:   pos.Filename,
+					Line:   pos.Line,
+				}
+				order = append(order, k)
+			}
+		}
+	}
+
+	var spilled []SpilledParam
+	for _, k := range order {
+		sort.Strings(found[k].Archs)
+		spilled = append(spilled, *found[k])
+	}
+	return spilled
+}
+
+func signatureParams(sig *types.Signature) []*types.Var {
+	var vars []*types.Var
+	if sig.Recv() != nil {
+		vars = append(vars, sig.Recv())
+	}
+	return append(vars, tupleVars(sig.Params())...)
+}
+
+func tupleVars(t *types.Tuple) []*types.Var {
+	vars := make([]*types.Var, t.Len())
+	for i := range vars {
+		vars[i] = t.At(i)
+	}
+	return vars
+}
@@ -0,0 +1,108 @@
+package structi
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/buarki/viztruct/internal/testmod"
+)
+
+func TestAssignRegisters(t *testing.T) {
+	tests := []struct {
+		name       string
+		src        string
+		arch       string
+		wantInRegs bool
+		wantInts   []string
+		wantFloats []string
+		wantReason string
+	}{
+		{
+			name:       "mixed fields",
+			src:        `type T struct { A int8; B string; C float64; D [1]int32 }`,
+			arch:       "amd64",
+			wantInRegs: true,
+			wantInts:   []string{"RAX=A", "RBX=B.ptr", "RCX=B.len", "RDI=D[0]"},
+			wantFloats: []string{"X0=C"},
+		},
+		{
+			name:       "array with many elements",
+			src:        `type T struct { A int; B [2]int }`,
+			arch:       "arm64",
+			wantReason: "B is an array of 2 elements",
+		},
+		{
+			name:       "out of registers on amd64",
+			src:        `type T struct { A, B, C, D, E, F, G, H, I, J int }`,
+			arch:       "amd64",
+			wantReason: "out of integer registers at J",
+		},
+		{
+			name:       "fits in registers on arm64",
+			src:        `type T struct { A, B, C, D, E, F, G, H, I, J int }`,
+			arch:       "arm64",
+			wantInRegs: true,
+			wantInts:   []string{"R0=A", "R1=B", "R2=C", "R3=D", "R4=E", "R5=F", "R6=G", "R7=H", "R8=I", "R9=J"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			infos, err := AnalyseStructs(tt.src)
+			if err != nil {
+				t.Fatalf("analyse error: %v", err)
+			}
+
+			got, err := infos[0].AssignRegisters(tt.arch)
+			if err != nil {
+				t.Fatalf("assign error: %v", err)
+			}
+
+			if got.InRegisters != tt.wantInRegs {
+				t.Errorf("in registers = %v, want %v", got.InRegisters, tt.wantInRegs)
+			}
+			if !reflect.DeepEqual(got.Ints, tt.wantInts) {
+				t.Errorf("ints = %v, want %v", got.Ints, tt.wantInts)
+			}
+			if !reflect.DeepEqual(got.Floats, tt.wantFloats) {
+				t.Errorf("floats = %v, want %v", got.Floats, tt.wantFloats)
+			}
+			if got.Reason != tt.wantReason {
+				t.Errorf("reason = %q, want %q", got.Reason, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestFindSpilledParams(t *testing.T) {
+	dir := testmod.Write(t, map[string]string{
+		"a/a.go": `package a
+
+type Small struct{ A, B int }
+
+type Big struct{ Buf [4]byte }
+
+func Use(s Small, b Big) Small { return s }
+
+func Many(a, b, c, d, e, f, g, h int, s Small) {}
+`,
+	})
+
+	pkgs, err := LoadPackages(LoadConfig{Dir: dir}, "./...")
+	if err != nil {
+		t.Fatalf("load error: %v", err)
+	}
+
+	spilled := FindSpilledParams(pkgs)
+	if len(spilled) != 2 {
+		t.Fatalf("expected 2 spilled params, got %d: %+v", len(spilled), spilled)
+	}
+
+	if p := spilled[0]; p.Func != "Use" || p.Param != "b" || !reflect.DeepEqual(p.Archs, []string{"amd64", "arm64"}) {
+		t.Errorf("unexpected spilled param: %+v", p)
+	}
+	// the first eight ints leave a single integer register on amd64 only
+	if p := spilled[1]; p.Func != "Many" || p.Param != "s" || !reflect.DeepEqual(p.Archs, []string{"amd64"}) {
+		t.Errorf("unexpected spilled param: %+v", p)
+	}
+}
@@ -0,0 +1,215 @@
+package structi
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"sort"
+)
+
+// AllocKind tells how a struct value gets instantiated at a site.
+type AllocKind string
+
+const (
+	AllocNew           AllocKind = "new"            // new(T)
+	AllocLiteral       AllocKind = "literal"        // &T{}
+	AllocSlice         AllocKind = "slice"          // make([]T, n) or []T{...}
+	AllocMap           AllocKind = "map"            // map[K]T
+	AllocChan          AllocKind = "chan"           // chan T
+	AllocEscapingLocal AllocKind = "escaping_local" // var v T whose address is taken
+)
+
+const (
+	// bulkAllocWeight is how many single values a container allocation is
+	// assumed to be worth when ranking structs by weighted waste.
+	bulkAllocWeight = 16
+	// stackAllocDivisor scales down sites the compiler proved to stay on
+	// the stack, their padding only costs stack space for a while.
+	stackAllocDivisor = 8
+)
+
+// Bulk reports whether the allocation holds many values at once.
+func (k AllocKind) Bulk() bool {
+	return k == AllocSlice || k == AllocMap || k == AllocChan
+}
+
+type AllocSite struct {
+	Kind   AllocKind `json:"kind"`
+	File   string    `json:"file"`
+	Line   int       `json:"line"`
+	Column int       `json:"column"`
+	Escape Escape    `json:"escape,omitempty"`
+}
+
+// AllocCounts returns the number of allocation sites per kind.
+func (i Info) AllocCounts() map[AllocKind]int {
+	counts := make(map[AllocKind]int)
+	for _, s := range i.AllocSites {
+		counts[s.Kind]++
+	}
+	return counts
+}
+
+// WeightedWaste is the number of bytes the optimized layout saves
+// multiplied by how often the struct is statically instantiated, with
+// container allocations weighing more than single values and stack
+// allocations weighing less than heap ones.
+func (i Info) WeightedWaste() int64 {
+	var weight int64
+	for _, s := range i.AllocSites {
+		w := int64(stackAllocDivisor)
+		if s.Kind.Bulk() {
+			w *= bulkAllocWeight
+		}
+		if s.Escape == EscapeStack {
+			w /= stackAllocDivisor
+		}
+		weight += w
+	}
+	return (i.OriginalSize - i.OptimizedSize) * weight / stackAllocDivisor
+}
+
+// SortByWeightedWaste orders structs so the ones worth fixing first come
+// first, falling back to the plain savings when weights are equal.
+func SortByWeightedWaste(infos []Info) {
+	sort.SliceStable(infos, func(a, b int) bool {
+		wa, wb := infos[a].WeightedWaste(), infos[b].WeightedWaste()
+		if wa != wb {
+			return wa > wb
+		}
+		return infos[a].OriginalSize-infos[a].OptimizedSize > infos[b].OriginalSize-infos[b].OptimizedSize
+	})
+}
+
+// AnalysePackages analyses every struct declared in the given packages
+// and attaches to each one the allocation sites found across all of them.
+func AnalysePackages(pkgs []*Package) ([]Info, error) {
+	var infos []Info
+	for _, pkg := range pkgs {
+		for _, file := range pkg.Files {
+			structs, err := analyzeNestedStructs(file, pkg.Sizes, pkg.TypesInfo, pkg.Fset)
+			if err != nil {
+				return nil, err
+			}
+			for i := range structs {
+				structs[i].Package = pkg.Path
+			}
+			infos = append(infos, structs...)
+		}
+	}
+
+	index := make(map[string]int)
+	for i, info := range infos {
+		index[info.QualifiedName()] = i
+	}
+
+	for _, pkg := range pkgs {
+		for _, site := range findAllocSites(pkg) {
+			if i, ok := index[site.typeName]; ok {
+				infos[i].AllocSites = append(infos[i].AllocSites, site.AllocSite)
+			}
+		}
+	}
+
+	return infos, nil
+}
+
+type typedAllocSite struct {
+	AllocSite
+	typeName string
+}
+
+func findAllocSites(pkg *Package) []typedAllocSite {
+	var sites []typedAllocSite
+	info := pkg.TypesInfo
+
+	add := func(kind AllocKind, t types.Type, pos token.Pos) {
+		name := qualifiedName(t)
+		if name == "" {
+			return
+		}
+		p := pkg.Fset.Position(pos)
+		sites = append(sites, typedAllocSite{
+			AllocSite: AllocSite{Kind: kind, File: p.Filename, Line: p.Line, Column: p.Column},
+			typeName:  name,
+		})
+	}
+
+	addContainer := func(t types.Type, pos token.Pos) {
+		switch u := t.Underlying().(type) {
+		case *types.Slice:
+			add(AllocSlice, u.Elem(), pos)
+		case *types.Map:
+			add(AllocMap, u.Elem(), pos)
+		case *types.Chan:
+			add(AllocChan, u.Elem(), pos)
+		}
+	}
+
+	escaped := make(map[*types.Var]bool)
+
+	for _, file := range pkg.Files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			switch e := n.(type) {
+			case *ast.CallExpr:
+				fn, ok := e.Fun.(*ast.Ident)
+				if !ok || len(e.Args) == 0 {
+					return true
+				}
+				if _, ok := info.Uses[fn].(*types.Builtin); !ok {
+					return true
+				}
+				t := info.TypeOf(e.Args[0])
+				if t == nil {
+					return true
+				}
+				// positions follow the ones the compiler uses in its
+				// escape analysis diagnostics
+				switch fn.Name {
+				case "new":
+					add(AllocNew, t, e.Lparen)
+				case "make":
+					addContainer(t, e.Lparen)
+				}
+			case *ast.CompositeLit:
+				if t := info.TypeOf(e); t != nil {
+					addContainer(t, e.Lbrace)
+				}
+			case *ast.UnaryExpr:
+				if e.Op != token.AND {
+					return true
+				}
+				switch x := e.X.(type) {
+				case *ast.CompositeLit:
+					if t := info.TypeOf(x); t != nil {
+						add(AllocLiteral, t, e.Pos())
+					}
+				case *ast.Ident:
+					v, ok := info.Uses[x].(*types.Var)
+					if !ok || v.IsField() || escaped[v] || v.Parent() == nil || v.Parent() == pkg.Types.Scope() {
+						return true
+					}
+					escaped[v] = true
+					add(AllocEscapingLocal, v.Type(), v.Pos())
+				}
+			}
+			return true
+		})
+	}
+
+	return sites
+}
+
+// qualifiedName returns the package qualified name of a named type, or an
+// empty string for any other type.
+func qualifiedName(t types.Type) string {
+	named, ok := types.Unalias(t).(*types.Named)
+	if !ok {
+		return ""
+	}
+	obj := named.Origin().Obj()
+	if obj.Pkg() == nil {
+		return ""
+	}
+	return obj.Pkg().Path() + "." + obj.Name()
+}
@@ -0,0 +1,134 @@
+package structi
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"sort"
+)
+
+// CopyKind tells how a struct value gets copied at a site.
+type CopyKind string
+
+const (
+	CopyParam    CopyKind = "param"     // passed by value, receivers included
+	CopyResult   CopyKind = "result"    // returned by value
+	CopyRange    CopyKind = "range"     // for _, v := range []T
+	CopyMapValue CopyKind = "map_value" // map[K]T stores values
+)
+
+// CopySite is a place where a large struct is copied by value.
+type CopySite struct {
+	Kind   CopyKind `json:"kind"`
+	Type   string   `json:"type"`
+	Bytes  int64    `json:"bytes"`
+	Name   string   `json:"name,omitempty"`
+	File   string   `json:"file"`
+	Line   int      `json:"line"`
+	Column int      `json:"column"`
+}
+
+// FindCopies scans the packages for structs bigger than threshold bytes
+// that are passed, returned, ranged over or stored in maps by value.
+func FindCopies(pkgs []*Package, threshold int64) []CopySite {
+	var sites []CopySite
+	for _, pkg := range pkgs {
+		sites = append(sites, findCopies(pkg, threshold)...)
+	}
+
+	sort.SliceStable(sites, func(i, j int) bool {
+		if sites[i].File != sites[j].File {
+			return sites[i].File < sites[j].File
+		}
+		if sites[i].Line != sites[j].Line {
+			return sites[i].Line < sites[j].Line
+		}
+		return sites[i].Column < sites[j].Column
+	})
+	return sites
+}
+
+func findCopies(pkg *Package, threshold int64) []CopySite {
+	var sites []CopySite
+	info := pkg.TypesInfo
+
+	add := func(kind CopyKind, t types.Type, name string, pos token.Pos) {
+		if t == nil {
+			return
+		}
+		if _, ok := t.Underlying().(*types.Struct); !ok {
+			return
+		}
+		if _, ok := t.(*types.TypeParam); ok {
+			return
+		}
+		size := pkg.Sizes.Sizeof(t)
+		if size <= threshold {
+			return
+		}
+		p := pkg.Fset.Position(pos)
+		sites = append(sites, CopySite{
+			Kind:   kind,
+			Type:   types.TypeString(t, types.RelativeTo(pkg.Types)),
+			Bytes:  size,
+			Name:   name,
+			File:   p.Filename,
+			Line:   p.Line,
+			Column: p.Column,
+		})
+	}
+
+	addFields := func(kind CopyKind, fields *ast.FieldList) {
+		if fields == nil {
+			return
+		}
+		for _, field := range fields.List {
+			t := info.TypeOf(field.Type)
+			if len(field.Names) == 0 {
+				add(kind, t, "", field.Type.Pos())
+			}
+			for _, name := range field.Names {
+				add(kind, t, name.Name, name.Pos())
+			}
+		}
+	}
+
+	// only functions copy their arguments, func types of declarations,
+	// interface methods or fields do not
+	addSignature := func(t *ast.FuncType) {
+		addFields(CopyParam, t.Params)
+		addFields(CopyResult, t.Results)
+	}
+
+	for _, file := range pkg.Files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			switch e := n.(type) {
+			case *ast.FuncDecl:
+				addFields(CopyParam, e.Recv)
+				addSignature(e.Type)
+			case *ast.FuncLit:
+				addSignature(e.Type)
+			case *ast.RangeStmt:
+				if e.Value == nil {
+					return true
+				}
+				if id, ok := e.Value.(*ast.Ident); ok && id.Name == "_" {
+					return true
+				}
+				add(CopyRange, info.TypeOf(e.Value), exprName(e.Value), e.Value.Pos())
+			case *ast.MapType:
+				add(CopyMapValue, info.TypeOf(e.Value), "", e.Value.Pos())
+			}
+			return true
+		})
+	}
+
+	return sites
+}
+
+func exprName(e ast.Expr) string {
+	if id, ok := e.(*ast.Ident); ok {
+		return id.Name
+	}
+	return ""
+}
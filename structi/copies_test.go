@@ -0,0 +1,76 @@
+package structi
+
+import (
+	"testing"
+
+	"github.com/buarki/viztruct/internal/testmod"
+)
+
+func TestFindCopies(t *testing.T) {
+	dir := testmod.Write(t, map[string]string{
+		"a/a.go": `package a
+
+type Big struct{ Buf [256]byte }
+
+type Small struct{ A int }
+
+func (b Big) Value() {}
+
+func Pass(b Big, s Small) Big { return b }
+
+var index map[string]Big
+
+func Walk(items []Big) {
+	for _, item := range items {
+		_ = item
+	}
+	for i := range items {
+		_ = items[i]
+	}
+}
+
+type Handler func(Big)
+
+type Store interface{ Get(key Big) Big }
+
+type Hooks struct{ OnBig func(Big) }
+
+var visit = func(v Big) {}
+`,
+	})
+
+	pkgs, err := LoadPackages(LoadConfig{Dir: dir}, "./...")
+	if err != nil {
+		t.Fatalf("load error: %v", err)
+	}
+
+	copies := FindCopies(pkgs, 128)
+
+	want := []struct {
+		kind CopyKind
+		name string
+		line int
+	}{
+		{kind: CopyParam, name: "b", line: 7},
+		{kind: CopyParam, name: "b", line: 9},
+		{kind: CopyResult, name: "", line: 9},
+		{kind: CopyMapValue, name: "", line: 11},
+		{kind: CopyRange, name: "item", line: 14},
+		// func types outside of functions copy nothing
+		{kind: CopyParam, name: "v", line: 28},
+	}
+
+	if len(copies) != len(want) {
+		t.Fatalf("expected %d copies, got %d: %+v", len(want), len(copies), copies)
+	}
+	for i, w := range want {
+		c := copies[i]
+		if c.Kind != w.kind || c.Name != w.name || c.Line != w.line || c.Bytes != 256 || c.Type != "Big" {
+			t.Errorf("copy[%d] = %+v, want %s %q at line %d", i, c, w.kind, w.name, w.line)
+		}
+	}
+
+	if got := FindCopies(pkgs, 256); len(got) != 0 {
+		t.Errorf("expected no copies at threshold 256, got %d", len(got))
+	}
+}
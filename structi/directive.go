@@ -0,0 +1,70 @@
+package structi
+
+import (
+	"go/ast"
+	"strconv"
+	"strings"
+)
+
+const directivePrefix = "//viztruct:"
+
+// Directive is a `//viztruct:name args` comment attached to a type
+// declaration.
+type Directive struct {
+	Name string `json:"name"`
+	Args string `json:"args,omitempty"`
+}
+
+// Directive returns the first directive with the given name.
+func (i Info) Directive(name string) (Directive, bool) {
+	for _, d := range i.Directives {
+		if d.Name == name {
+			return d, true
+		}
+	}
+	return Directive{}, false
+}
+
+// Arg returns the value of a key=value argument of the directive, values
+// holding spaces are quoted: reason="wire format".
+func (d Directive) Arg(key string) (string, bool) {
+	rest := d.Args
+	for rest != "" {
+		var k, v string
+		k, rest, _ = strings.Cut(strings.TrimSpace(rest), "=")
+		if strings.HasPrefix(rest, `"`) {
+			quoted, err := strconv.QuotedPrefix(rest)
+			if err != nil {
+				return "", false
+			}
+			v, _ = strconv.Unquote(quoted)
+			rest = rest[len(quoted):]
+		} else {
+			v, rest, _ = strings.Cut(rest, " ")
+		}
+		if k == key {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+func parseDirectives(groups ...*ast.CommentGroup) []Directive {
+	var directives []Directive
+	for _, group := range groups {
+		if group == nil {
+			continue
+		}
+		for _, c := range group.List {
+			if !strings.HasPrefix(c.Text, directivePrefix) {
+				continue
+			}
+			name, args, _ := strings.Cut(strings.TrimPrefix(c.Text, directivePrefix), " ")
+			if name == "" {
+				continue
+			}
+			directives = append(directives, Directive{Name: name, Args: strings.TrimSpace(args)})
+		}
+	}
+	return directives
+}
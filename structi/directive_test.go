@@ -0,0 +1,55 @@
+package structi
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseDirectives(t *testing.T) {
+	input := `package main
+
+// Header is written as is to disk.
+//
+//viztruct:stable
+//viztruct:maxsize 64
+type Header struct {
+	Magic uint32
+}
+
+type Plain struct {
+	A int
+}
+`
+	infos, err := AnalyseStructs(input)
+	if err != nil {
+		t.Fatalf("analyse error: %v", err)
+	}
+	if len(infos) != 2 {
+		t.Fatalf("expected 2 structs, got %d", len(infos))
+	}
+
+	want := []Directive{{Name: "stable"}, {Name: "maxsize", Args: "64"}}
+	if !reflect.DeepEqual(infos[0].Directives, want) {
+		t.Errorf("got directives %+v, want %+v", infos[0].Directives, want)
+	}
+	if d, ok := infos[0].Directive("maxsize"); !ok || d.Args != "64" {
+		t.Errorf("maxsize directive not found, got %+v", d)
+	}
+	if _, ok := infos[1].Directive("stable"); ok {
+		t.Errorf("Plain should have no directive")
+	}
+}
+
+func TestDirectiveArg(t *testing.T) {
+	d := Directive{Name: "ignore", Args: `reason="wire format" owner=net`}
+
+	if v, ok := d.Arg("reason"); !ok || v != "wire format" {
+		t.Errorf("got reason %q, %v", v, ok)
+	}
+	if v, ok := d.Arg("owner"); !ok || v != "net" {
+		t.Errorf("got owner %q, %v", v, ok)
+	}
+	if _, ok := d.Arg("missing"); ok {
+		t.Errorf("missing argument found")
+	}
+}
@@ -0,0 +1,258 @@
+package structi
+
+import (
+	"debug/dwarf"
+	"debug/elf"
+	"encoding/binary"
+	"fmt"
+	"go/types"
+	"sort"
+	"strings"
+)
+
+// AnalyseBinary reads the struct layouts recorded in the DWARF debug info
+// of an ELF binary or object file. Only structs whose qualified name is
+// accepted by include are returned, all of them when include is nil.
+func AnalyseBinary(path string, include func(name string) bool) ([]Info, error) {
+	file, err := elf.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open ELF file: %v", err)
+	}
+	defer file.Close()
+
+	data, err := file.DWARF()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read debug info (was it built with -ldflags=-w?): %v", err)
+	}
+
+	return AnalyseDWARF(data, elfSizes(file), include)
+}
+
+// elfSizes returns the sizes of the architecture the file was built for,
+// guessed from its word size when the machine is not one Go supports.
+func elfSizes(file *elf.File) types.Sizes {
+	arch := ""
+	little := file.ByteOrder == binary.LittleEndian
+	switch file.Machine {
+	case elf.EM_X86_64:
+		arch = "amd64"
+	case elf.EM_386:
+		arch = "386"
+	case elf.EM_ARM:
+		arch = "arm"
+	case elf.EM_AARCH64:
+		arch = "arm64"
+	case elf.EM_RISCV:
+		arch = "riscv64"
+	case elf.EM_LOONGARCH:
+		arch = "loong64"
+	case elf.EM_S390:
+		arch = "s390x"
+	case elf.EM_PPC64:
+		arch = "ppc64"
+		if little {
+			arch = "ppc64le"
+		}
+	case elf.EM_MIPS:
+		arch = "mips"
+		if file.Class == elf.ELFCLASS64 {
+			arch = "mips64"
+		}
+		if little {
+			arch += "le"
+		}
+	}
+
+	if sizes := types.SizesFor("gc", arch); sizes != nil {
+		return sizes
+	}
+	if file.Class == elf.ELFCLASS32 {
+		return &types.StdSizes{WordSize: 4, MaxAlign: 4}
+	}
+	return &customSizes
+}
+
+// AnalyseDWARF builds the Info of every named struct type in the debug
+// info. Offsets and sizes are the ones recorded by the compiler, the
+// alignment of each field is derived from its type and the sizes of the
+// architecture the debug info was built for.
+func AnalyseDWARF(data *dwarf.Data, sizes types.Sizes, include func(name string) bool) ([]Info, error) {
+	var infos []Info
+	seen := make(map[string]bool)
+
+	reader := data.Reader()
+	for {
+		entry, err := reader.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read debug info: %v", err)
+		}
+		if entry == nil {
+			break
+		}
+		if entry.Tag != dwarf.TagStructType {
+			continue
+		}
+
+		// nested types are reached through the struct itself
+		reader.SkipChildren()
+
+		name, _ := entry.Val(dwarf.AttrName).(string)
+		if name == "" || compilerShape(name) || seen[name] {
+			continue
+		}
+		if declaration, _ := entry.Val(dwarf.AttrDeclaration).(bool); declaration {
+			continue
+		}
+		if include != nil && !include(name) {
+			continue
+		}
+
+		typ, err := data.Type(entry.Offset)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read type %s: %v", name, err)
+		}
+		st, ok := typ.(*dwarf.StructType)
+		if !ok || st.Incomplete || st.Kind != "struct" {
+			continue
+		}
+		seen[name] = true
+
+		infos = append(infos, dwarfInfo(name, st, sizes))
+	}
+
+	sort.SliceStable(infos, func(i, j int) bool {
+		if infos[i].Package != infos[j].Package {
+			return infos[i].Package < infos[j].Package
+		}
+		return infos[i].Name < infos[j].Name
+	})
+
+	return infos, nil
+}
+
+// compilerShape reports whether a struct describes how the compiler lays
+// out a builtin type (slices, strings, maps, channels...) rather than a
+// type declared in the source.
+func compilerShape(name string) bool {
+	if name == "string" {
+		return true
+	}
+	for _, prefix := range []string{"struct {", "[]", "map[", "chan ", "noalg."} {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	// map internals are named like groupReference<string,int> and generic
+	// code shared between instantiations uses go.shape types
+	return strings.Contains(name, "<") || strings.Contains(name, "go.shape.")
+}
+
+func dwarfInfo(qualifiedName string, st *dwarf.StructType, sizes types.Sizes) Info {
+	var fields []Field
+	for _, f := range st.Field {
+		fields = append(fields, Field{
+			Name:     f.Name,
+			TypeName: dwarfTypeName(f.Type),
+			Kind:     dwarfKind(f.Type),
+			Offset:   f.ByteOffset,
+			Size:     f.Type.Size(),
+			Align:    dwarfAlign(f.Type, sizes),
+		})
+	}
+
+	info := layoutInfo("", padFields(fields, st.ByteSize), optimizeFields(fields))
+	info.Package, info.Name = splitQualifiedName(qualifiedName)
+	return info
+}
+
+// padFields inserts the padding found between fields placed at known
+// offsets and after the last one up to the struct size.
+func padFields(fields []Field, size int64) []Field {
+	var padded []Field
+	var offset int64
+
+	for _, f := range fields {
+		if f.Offset > offset {
+			padded = append(padded, Field{
+				Name:      "padding",
+				Offset:    offset,
+				Size:      f.Offset - offset,
+				Align:     1,
+				IsPadding: true,
+			})
+		}
+		padded = append(padded, f)
+		if end := f.Offset + f.Size; end > offset {
+			offset = end
+		}
+	}
+
+	if size > offset {
+		padded = append(padded, Field{
+			Name:      "tail padding",
+			Offset:    offset,
+			Size:      size - offset,
+			Align:     1,
+			IsPadding: true,
+		})
+	}
+
+	return padded
+}
+
+func dwarfTypeName(t dwarf.Type) string {
+	if st, ok := t.(*dwarf.StructType); ok && st.StructName != "" {
+		return st.StructName
+	}
+	if name := t.Common().Name; name != "" {
+		return name
+	}
+	return t.String()
+}
+
+// dwarfAlign derives the alignment of a type, DWARF does not record it.
+// No type is aligned past the alignment of int64 on the architecture.
+func dwarfAlign(t dwarf.Type, sizes types.Sizes) int64 {
+	maxAlign := sizes.Alignof(types.Typ[types.Int64])
+	switch t := t.(type) {
+	case *dwarf.StructType:
+		align := int64(1)
+		for _, f := range t.Field {
+			if a := dwarfAlign(f.Type, sizes); a > align {
+				align = a
+			}
+		}
+		return align
+	case *dwarf.ArrayType:
+		return dwarfAlign(t.Type, sizes)
+	case *dwarf.TypedefType:
+		return dwarfAlign(t.Type, sizes)
+	case *dwarf.QualType:
+		return dwarfAlign(t.Type, sizes)
+	case *dwarf.ComplexType:
+		return min(t.Size()/2, maxAlign)
+	}
+
+	size := t.Size()
+	if size <= 0 {
+		return 1
+	}
+	return min(size, maxAlign)
+}
+
+// splitQualifiedName splits "example.com/pkg.Type" into its package and
+// type name. C types have no package.
+func splitQualifiedName(name string) (string, string) {
+	base := name
+	if i := strings.Index(base, "["); i >= 0 {
+		base = base[:i] // type arguments may contain dots
+	}
+
+	slash := strings.LastIndex(base, "/")
+	dot := strings.Index(base[slash+1:], ".")
+	if dot < 0 {
+		return "", name
+	}
+	dot += slash + 1
+	return name[:dot], name[dot+1:]
+}
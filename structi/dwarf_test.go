@@ -0,0 +1,190 @@
+package structi
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/buarki/viztruct/internal/testmod"
+)
+
+func TestAnalyseBinary(t *testing.T) {
+	dir := testmod.Write(t, map[string]string{
+		"main.go": `package main
+
+type Sample struct {
+	A bool
+	B int64
+	C bool
+	D string
+}
+
+var sink = &Sample{}
+
+func main() { println(sink) }
+`,
+	})
+
+	exe := filepath.Join(dir, "sample")
+	cmd := exec.Command("go", "build", "-o", exe, ".")
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("build failed: %v: %s", err, out)
+	}
+
+	infos, err := AnalyseBinary(exe, func(n string) bool { return n == "main.Sample" })
+	if err != nil {
+		t.Fatalf("analyse error: %v", err)
+	}
+	if len(infos) != 1 {
+		t.Fatalf("expected 1 struct, got %d", len(infos))
+	}
+
+	info := infos[0]
+	if info.Name != "Sample" || info.Package != "main" {
+		t.Errorf("unexpected name %s.%s", info.Package, info.Name)
+	}
+
+	// must match what the source based analysis computes
+	fromSource, err := AnalyseStructs(`type Sample struct {
+		A bool
+		B int64
+		C bool
+		D string
+	}`)
+	if err != nil {
+		t.Fatalf("analyse error: %v", err)
+	}
+	want := fromSource[0]
+
+	if info.OriginalSize != want.OriginalSize || info.OptimizedSize != want.OptimizedSize || info.WastedBytes != want.WastedBytes {
+		t.Errorf("sizes = %d/%d/%d, want %d/%d/%d", info.OriginalSize, info.OptimizedSize, info.WastedBytes,
+			want.OriginalSize, want.OptimizedSize, want.WastedBytes)
+	}
+	if len(info.Fields) != len(want.Fields) {
+		t.Fatalf("expected %d fields, got %d", len(want.Fields), len(info.Fields))
+	}
+	for i, f := range info.Fields {
+		w := want.Fields[i]
+		if f.Name != w.Name || f.Offset != w.Offset || f.Size != w.Size || f.IsPadding != w.IsPadding || f.Kind != w.Kind {
+			t.Errorf("field[%d] = %+v, want %+v", i, f, w)
+		}
+	}
+}
+
+func TestSplitQualifiedName(t *testing.T) {
+	tests := []struct {
+		in, pkg, name string
+	}{
+		{in: "main.T", pkg: "main", name: "T"},
+		{in: "github.com/a/b.T", pkg: "github.com/a/b", name: "T"},
+		{in: "example.com/x.Pair[example.com/y.K,int]", pkg: "example.com/x", name: "Pair[example.com/y.K,int]"},
+		{in: "stat", pkg: "", name: "stat"},
+	}
+
+	for _, tt := range tests {
+		pkg, name := splitQualifiedName(tt.in)
+		if pkg != tt.pkg || name != tt.name {
+			t.Errorf("splitQualifiedName(%q) = %q, %q, want %q, %q", tt.in, pkg, name, tt.pkg, tt.name)
+		}
+	}
+}
+
+func TestAnalyseBinaryKeepsStringsTypes(t *testing.T) {
+	dir := testmod.Write(t, map[string]string{
+		"main.go": `package main
+
+import "strings"
+
+type Output struct {
+	Done bool
+	Buf  strings.Builder
+}
+
+var sink = &Output{}
+
+func main() { sink.Buf.WriteString("x"); println(sink) }
+`,
+	})
+
+	exe := filepath.Join(dir, "sample")
+	cmd := exec.Command("go", "build", "-o", exe, ".")
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("build failed: %v: %s", err, out)
+	}
+
+	infos, err := AnalyseBinary(exe, func(n string) bool { return n == "main.Output" || n == "strings.Builder" })
+	if err != nil {
+		t.Fatalf("analyse error: %v", err)
+	}
+
+	var names []string
+	for _, info := range infos {
+		names = append(names, info.Package+"."+info.Name)
+	}
+	if len(names) != 2 || names[0] != "main.Output" || names[1] != "strings.Builder" {
+		t.Errorf("structs = %v, want [main.Output strings.Builder]", names)
+	}
+}
+
+func TestAnalyseBinaryAlignsForItsArch(t *testing.T) {
+	dir := testmod.Write(t, map[string]string{
+		"main.go": `package main
+
+type Sample struct {
+	A bool
+	B int64
+	C bool
+}
+
+var sink = &Sample{}
+
+func main() { println(sink) }
+`,
+	})
+
+	exe := filepath.Join(dir, "sample")
+	cmd := exec.Command("go", "build", "-o", exe, ".")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOOS=linux", "GOARCH=386", "CGO_ENABLED=0")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("build failed: %v: %s", err, out)
+	}
+
+	infos, err := AnalyseBinary(exe, func(n string) bool { return n == "main.Sample" })
+	if err != nil {
+		t.Fatalf("analyse error: %v", err)
+	}
+	if len(infos) != 1 {
+		t.Fatalf("expected 1 struct, got %d", len(infos))
+	}
+
+	// int64 is 4 byte aligned on 386
+	info := infos[0]
+	if info.OriginalSize != 16 || info.OptimizedSize != 12 {
+		t.Errorf("sizes = %d/%d, want 16/12", info.OriginalSize, info.OptimizedSize)
+	}
+}
+
+func TestCompilerShape(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{name: "string", want: true},
+		{name: "[]int", want: true},
+		{name: "struct { a int }", want: true},
+		{name: "map[string]int", want: true},
+		{name: "strings.Builder", want: false},
+		{name: "stringutil.Buffer", want: false},
+		{name: "main.T", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := compilerShape(tt.name); got != tt.want {
+			t.Errorf("compilerShape(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
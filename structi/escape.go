@@ -0,0 +1,108 @@
+package structi
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Escape tells where the compiler decided to allocate a value.
+type Escape string
+
+const (
+	EscapeHeap  Escape = "heap"
+	EscapeStack Escape = "stack"
+)
+
+// compiler diagnostics look like "./a.go:12:10: new(T) escapes to heap"
+var escapeDiagnostic = regexp.MustCompile(`^(.+?):(\d+):(\d+): (.*)$`)
+
+type sourcePos struct {
+	file         string
+	line, column int
+}
+
+// AnnotateEscapes runs the compiler escape analysis (-gcflags=-m) over the
+// packages and records for each allocation site whether it ends up on the
+// heap or on the stack. A struct is marked as heap allocated as soon as one
+// of its sites escapes and as stack allocated when none of them does.
+func AnnotateEscapes(pkgs []*Package, infos []Info) error {
+	escapes := make(map[sourcePos]Escape)
+	for _, pkg := range pkgs {
+		if err := compileWithEscapeAnalysis(pkg, escapes); err != nil {
+			return err
+		}
+	}
+
+	for i := range infos {
+		info := &infos[i]
+		info.Escape = ""
+		for j := range info.AllocSites {
+			site := &info.AllocSites[j]
+			site.Escape = escapes[sourcePos{file: site.File, line: site.Line, column: site.Column}]
+
+			switch {
+			case site.Escape == EscapeHeap:
+				info.Escape = EscapeHeap
+			case site.Escape == EscapeStack && info.Escape == "":
+				info.Escape = EscapeStack
+			}
+		}
+	}
+
+	return nil
+}
+
+func compileWithEscapeAnalysis(pkg *Package, escapes map[sourcePos]Escape) error {
+	// built from the package directory so reported paths are relative to it,
+	// the compiled output itself is not needed
+	cmd := exec.Command("go", "build", "-gcflags=-m", "-o", os.DevNull, pkg.Path)
+	cmd.Dir = pkg.Dir
+	cmd.Env = append(os.Environ(), "GOARCH="+pkg.GOARCH)
+
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("escape analysis of %s failed: %v: %s", pkg.Path, err, bytes.TrimSpace(output.Bytes()))
+	}
+
+	scanner := bufio.NewScanner(&output)
+	for scanner.Scan() {
+		m := escapeDiagnostic.FindStringSubmatch(scanner.Text())
+		if m == nil {
+			continue
+		}
+
+		var escape Escape
+		switch msg := m[4]; {
+		case strings.HasSuffix(msg, " escapes to heap"), strings.HasPrefix(msg, "moved to heap: "):
+			escape = EscapeHeap
+		case strings.HasSuffix(msg, " does not escape"):
+			escape = EscapeStack
+		default:
+			continue
+		}
+
+		file := m[1]
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(pkg.Dir, file)
+		}
+		line, _ := strconv.Atoi(m[2])
+		column, _ := strconv.Atoi(m[3])
+
+		pos := sourcePos{file: file, line: line, column: column}
+		// inlined copies of a function may disagree, any heap copy wins
+		if escapes[pos] != EscapeHeap {
+			escapes[pos] = escape
+		}
+	}
+
+	return scanner.Err()
+}
@@ -0,0 +1,141 @@
+package structi
+
+import (
+	"debug/dwarf"
+	"go/types"
+	"reflect"
+	"strings"
+)
+
+// Kind classifies the type of a field by its underlying type, so named
+// types like time.Duration are seen as the integer they are.
+type Kind string
+
+const (
+	KindBool      Kind = "bool"
+	KindInt       Kind = "int"
+	KindFloat     Kind = "float"
+	KindComplex   Kind = "complex"
+	KindString    Kind = "string"
+	KindPointer   Kind = "pointer"
+	KindSlice     Kind = "slice"
+	KindMap       Kind = "map"
+	KindChan      Kind = "chan"
+	KindFunc      Kind = "func"
+	KindInterface Kind = "interface"
+	KindStruct    Kind = "struct"
+	KindArray     Kind = "array"
+)
+
+func kindOf(t types.Type) Kind {
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		info := u.Info()
+		switch {
+		case info&types.IsBoolean != 0:
+			return KindBool
+		case info&types.IsInteger != 0:
+			return KindInt
+		case info&types.IsFloat != 0:
+			return KindFloat
+		case info&types.IsComplex != 0:
+			return KindComplex
+		case info&types.IsString != 0:
+			return KindString
+		case u.Kind() == types.UnsafePointer:
+			return KindPointer
+		}
+	case *types.Pointer:
+		return KindPointer
+	case *types.Slice:
+		return KindSlice
+	case *types.Map:
+		return KindMap
+	case *types.Chan:
+		return KindChan
+	case *types.Signature:
+		return KindFunc
+	case *types.Interface:
+		return KindInterface
+	case *types.Struct:
+		return KindStruct
+	case *types.Array:
+		return KindArray
+	}
+	return ""
+}
+
+func reflectKind(t reflect.Type) Kind {
+	switch t.Kind() {
+	case reflect.Bool:
+		return KindBool
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return KindInt
+	case reflect.Float32, reflect.Float64:
+		return KindFloat
+	case reflect.Complex64, reflect.Complex128:
+		return KindComplex
+	case reflect.String:
+		return KindString
+	case reflect.Pointer, reflect.UnsafePointer:
+		return KindPointer
+	case reflect.Slice:
+		return KindSlice
+	case reflect.Map:
+		return KindMap
+	case reflect.Chan:
+		return KindChan
+	case reflect.Func:
+		return KindFunc
+	case reflect.Interface:
+		return KindInterface
+	case reflect.Struct:
+		return KindStruct
+	case reflect.Array:
+		return KindArray
+	}
+	return ""
+}
+
+// dwarfKind recognizes the builtin types the compiler describes as
+// structs or pointers by their Go name.
+func dwarfKind(t dwarf.Type) Kind {
+	name := dwarfTypeName(t)
+	switch {
+	case name == "string":
+		return KindString
+	case strings.HasPrefix(name, "[]"):
+		return KindSlice
+	case strings.HasPrefix(name, "map["):
+		return KindMap
+	case strings.HasPrefix(name, "chan "), strings.HasPrefix(name, "<-chan "):
+		return KindChan
+	case strings.HasPrefix(name, "func("):
+		return KindFunc
+	}
+
+	switch t := t.(type) {
+	case *dwarf.TypedefType:
+		return dwarfKind(t.Type)
+	case *dwarf.BoolType:
+		return KindBool
+	case *dwarf.IntType, *dwarf.UintType, *dwarf.CharType, *dwarf.UcharType:
+		return KindInt
+	case *dwarf.FloatType:
+		return KindFloat
+	case *dwarf.ComplexType:
+		return KindComplex
+	case *dwarf.PtrType, *dwarf.UnspecifiedType:
+		return KindPointer
+	case *dwarf.ArrayType:
+		return KindArray
+	case *dwarf.StructType:
+		// interfaces are laid out as the runtime iface and eface structs
+		if t.StructName == "runtime.iface" || t.StructName == "runtime.eface" {
+			return KindInterface
+		}
+		return KindStruct
+	}
+	return ""
+}
@@ -0,0 +1,212 @@
+package structi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Package is a type-checked Go package loaded from disk.
+type Package struct {
+	Path      string
+	Name      string
+	Dir       string
+	Fset      *token.FileSet
+	Files     []*ast.File
+	Types     *types.Package
+	TypesInfo *types.Info
+	Sizes     types.Sizes
+	GOARCH    string
+	// SFiles are the assembly files built for GOARCH, relative to Dir.
+	SFiles []string
+}
+
+// LoadConfig controls how packages are loaded.
+type LoadConfig struct {
+	// Dir is the directory the patterns are resolved from, the current
+	// directory when empty.
+	Dir string
+	// GOARCH selects the architecture used to pick files and compute
+	// sizes, the host architecture when empty.
+	GOARCH string
+	// Tags are extra build tags, used to leave generated files out.
+	Tags []string
+}
+
+func (c LoadConfig) goarch() string {
+	if c.GOARCH != "" {
+		return c.GOARCH
+	}
+	return runtime.GOARCH
+}
+
+// WordSize returns the size of a pointer on arch, 8 bytes when the
+// architecture is unknown.
+func WordSize(arch string) int64 {
+	sizes := types.SizesFor("gc", arch)
+	if sizes == nil {
+		return customSizes.WordSize
+	}
+	return sizes.Sizeof(types.Typ[types.Uintptr])
+}
+
+// listedPackage is the subset of `go list -json` output we rely on.
+type listedPackage struct {
+	ImportPath string
+	Name       string
+	Dir        string
+	Export     string
+	GoFiles    []string
+	CgoFiles   []string
+	SFiles     []string
+	ImportMap  map[string]string
+	DepOnly    bool
+	Error      *struct {
+		Err string
+	}
+}
+
+// LoadPackages loads and type-checks the packages matching the given
+// patterns (as understood by `go list`). Dependencies are read from the
+// export data produced by the go command, so it must be on the PATH.
+func LoadPackages(cfg LoadConfig, patterns ...string) ([]*Package, error) {
+	if len(patterns) == 0 {
+		patterns = []string{"."}
+	}
+
+	listed, err := goList(cfg, patterns)
+	if err != nil {
+		return nil, err
+	}
+
+	exports := make(map[string]string)
+	for _, lp := range listed {
+		if lp.Export != "" {
+			exports[lp.ImportPath] = lp.Export
+		}
+	}
+
+	sizes := types.SizesFor("gc", cfg.goarch())
+	if sizes == nil {
+		return nil, fmt.Errorf("unsupported architecture: %s", cfg.goarch())
+	}
+
+	var pkgs []*Package
+	for _, lp := range listed {
+		if lp.DepOnly {
+			continue
+		}
+		if lp.Error != nil && len(lp.GoFiles) == 0 {
+			return nil, fmt.Errorf("failed to load %s: %s", lp.ImportPath, lp.Error.Err)
+		}
+
+		// the go command also fails on the package when its assembly does
+		// not build, the Go files may still type-check fine on their own
+		pkg, err := checkPackage(lp, exports, sizes)
+		if err != nil && lp.Error != nil {
+			return nil, fmt.Errorf("failed to load %s: %s", lp.ImportPath, lp.Error.Err)
+		}
+		if err != nil {
+			return nil, err
+		}
+		pkg.GOARCH = cfg.goarch()
+		pkgs = append(pkgs, pkg)
+	}
+
+	return pkgs, nil
+}
+
+func goList(cfg LoadConfig, patterns []string) ([]listedPackage, error) {
+	args := []string{"list", "-e", "-json", "-export", "-deps"}
+	if len(cfg.Tags) > 0 {
+		args = append(args, "-tags", strings.Join(cfg.Tags, ","))
+	}
+	args = append(append(args, "--"), patterns...)
+	cmd := exec.Command("go", args...)
+	cmd.Dir = cfg.Dir
+	cmd.Env = append(os.Environ(), "GOARCH="+cfg.goarch())
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("go list failed: %v: %s", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+
+	var listed []listedPackage
+	dec := json.NewDecoder(&stdout)
+	for {
+		var lp listedPackage
+		if err := dec.Decode(&lp); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to decode go list output: %v", err)
+		}
+		listed = append(listed, lp)
+	}
+
+	return listed, nil
+}
+
+func checkPackage(lp listedPackage, exports map[string]string, sizes types.Sizes) (*Package, error) {
+	fset := token.NewFileSet()
+
+	var files []*ast.File
+	for _, name := range append(lp.GoFiles, lp.CgoFiles...) {
+		file, err := parser.ParseFile(fset, filepath.Join(lp.Dir, name), nil, parser.ParseComments)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %v", name, err)
+		}
+		files = append(files, file)
+	}
+
+	lookup := func(path string) (io.ReadCloser, error) {
+		if mapped, ok := lp.ImportMap[path]; ok {
+			path = mapped
+		}
+		export, ok := exports[path]
+		if !ok {
+			return nil, fmt.Errorf("no export data for %s", path)
+		}
+		return os.Open(export)
+	}
+
+	conf := types.Config{
+		Importer:    importer.ForCompiler(fset, "gc", lookup),
+		Sizes:       sizes,
+		FakeImportC: true,
+	}
+	info := &types.Info{
+		Types: make(map[ast.Expr]types.TypeAndValue),
+		Defs:  make(map[*ast.Ident]types.Object),
+		Uses:  make(map[*ast.Ident]types.Object),
+	}
+
+	tpkg, err := conf.Check(lp.ImportPath, fset, files, info)
+	if err != nil {
+		return nil, fmt.Errorf("failed to type-check %s: %v", lp.ImportPath, err)
+	}
+
+	return &Package{
+		Path:      lp.ImportPath,
+		Name:      lp.Name,
+		Dir:       lp.Dir,
+		Fset:      fset,
+		Files:     files,
+		Types:     tpkg,
+		TypesInfo: info,
+		Sizes:     sizes,
+		SFiles:    lp.SFiles,
+	}, nil
+}
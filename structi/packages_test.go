@@ -0,0 +1,209 @@
+package structi
+
+import (
+	"testing"
+
+	"github.com/buarki/viztruct/internal/testmod"
+)
+
+func TestAnalysePackagesAllocSites(t *testing.T) {
+	dir := testmod.Write(t, map[string]string{
+		"a/a.go": `package a
+
+type Hot struct {
+	A bool
+	B int64
+	C bool
+}
+
+type Cold struct {
+	A bool
+	B int64
+	C bool
+}
+
+func build() {
+	_ = new(Hot)
+	_ = &Hot{}
+	_ = make([]Hot, 10)
+	_ = map[string]Hot{}
+	_ = make(chan Hot)
+	var local Hot
+	_ = &local
+	_ = &local
+	_ = Cold{}
+}
+`,
+		"b/b.go": `package b
+
+import "example.com/sample/a"
+
+var Shared = []a.Hot{}
+`,
+	})
+
+	pkgs, err := LoadPackages(LoadConfig{Dir: dir, GOARCH: "amd64"}, "./...")
+	if err != nil {
+		t.Fatalf("load error: %v", err)
+	}
+	if len(pkgs) != 2 {
+		t.Fatalf("expected 2 packages, got %d", len(pkgs))
+	}
+
+	infos, err := AnalysePackages(pkgs)
+	if err != nil {
+		t.Fatalf("analyse error: %v", err)
+	}
+
+	byName := make(map[string]Info)
+	for _, info := range infos {
+		byName[info.Name] = info
+	}
+
+	hot := byName["Hot"]
+	if hot.Package != "example.com/sample/a" {
+		t.Errorf("unexpected package %q", hot.Package)
+	}
+	if hot.Line != 3 {
+		t.Errorf("expected Hot declared at line 3, got %d", hot.Line)
+	}
+
+	want := map[AllocKind]int{
+		AllocNew:           1,
+		AllocLiteral:       1,
+		AllocSlice:         2,
+		AllocMap:           1,
+		AllocChan:          1,
+		AllocEscapingLocal: 1,
+	}
+	got := hot.AllocCounts()
+	for kind, n := range want {
+		if got[kind] != n {
+			t.Errorf("%s allocations = %d, want %d", kind, got[kind], n)
+		}
+	}
+
+	if cold := byName["Cold"]; len(cold.AllocSites) != 0 {
+		t.Errorf("expected no allocation sites for Cold, got %v", cold.AllocSites)
+	}
+
+	SortByWeightedWaste(infos)
+	if infos[0].Name != "Hot" {
+		t.Errorf("expected Hot to rank first, got %s", infos[0].Name)
+	}
+	// 8 saved bytes, 3 single values and 4 bulk allocations
+	if w := hot.WeightedWaste(); w != 8*(3+4*bulkAllocWeight) {
+		t.Errorf("weighted waste = %d, want %d", w, 8*(3+4*bulkAllocWeight))
+	}
+}
+
+func TestAnnotateEscapes(t *testing.T) {
+	dir := testmod.Write(t, map[string]string{
+		"a/a.go": `package a
+
+type Heap struct {
+	A bool
+	B int64
+	C bool
+}
+
+type Stack struct {
+	A bool
+	B int64
+	C bool
+}
+
+var sink *Heap
+
+func use() int64 {
+	sink = &Heap{}
+	s := &Stack{}
+	return s.B
+}
+`,
+	})
+
+	pkgs, err := LoadPackages(LoadConfig{Dir: dir}, "./...")
+	if err != nil {
+		t.Fatalf("load error: %v", err)
+	}
+	infos, err := AnalysePackages(pkgs)
+	if err != nil {
+		t.Fatalf("analyse error: %v", err)
+	}
+	if err := AnnotateEscapes(pkgs, infos); err != nil {
+		t.Fatalf("escape analysis error: %v", err)
+	}
+
+	want := map[string]Escape{"Heap": EscapeHeap, "Stack": EscapeStack}
+	for _, info := range infos {
+		if info.Escape != want[info.Name] {
+			t.Errorf("%s escape = %q, want %q", info.Name, info.Escape, want[info.Name])
+		}
+	}
+
+	heap, stack := infos[0], infos[1]
+	if heap.WeightedWaste() <= stack.WeightedWaste() {
+		t.Errorf("expected heap struct to weigh more: %d <= %d", heap.WeightedWaste(), stack.WeightedWaste())
+	}
+}
+
+func TestAnnotateEscapesBuiltins(t *testing.T) {
+	dir := testmod.Write(t, map[string]string{
+		"a/a.go": `package a
+
+type NewHeap struct{ A, B int64 }
+
+type NewStack struct{ A, B int64 }
+
+type MakeHeap struct{ A, B int64 }
+
+type MakeStack struct{ A, B int64 }
+
+var sink *NewHeap
+
+var items []MakeHeap
+
+func use(n int) int64 {
+	sink = new(NewHeap)
+	s := new(NewStack)
+	items = make([]MakeHeap, n)
+	m := make([]MakeStack, 4)
+	return s.A + m[0].B
+}
+`,
+	})
+
+	pkgs, err := LoadPackages(LoadConfig{Dir: dir}, "./...")
+	if err != nil {
+		t.Fatalf("load error: %v", err)
+	}
+	infos, err := AnalysePackages(pkgs)
+	if err != nil {
+		t.Fatalf("analyse error: %v", err)
+	}
+	if err := AnnotateEscapes(pkgs, infos); err != nil {
+		t.Fatalf("escape analysis error: %v", err)
+	}
+
+	want := map[string]struct {
+		kind   AllocKind
+		escape Escape
+	}{
+		"NewHeap":   {AllocNew, EscapeHeap},
+		"NewStack":  {AllocNew, EscapeStack},
+		"MakeHeap":  {AllocSlice, EscapeHeap},
+		"MakeStack": {AllocSlice, EscapeStack},
+	}
+	for _, info := range infos {
+		w := want[info.Name]
+		if len(info.AllocSites) != 1 {
+			t.Errorf("%s has %d allocation sites, want 1", info.Name, len(info.AllocSites))
+			continue
+		}
+		// the site must sit where the compiler reports the allocation
+		if site := info.AllocSites[0]; site.Kind != w.kind || site.Escape != w.escape {
+			t.Errorf("%s site = %s/%q, want %s/%q", info.Name, site.Kind, site.Escape, w.kind, w.escape)
+		}
+	}
+}
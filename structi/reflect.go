@@ -0,0 +1,48 @@
+package structi
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// AnalyseType builds the Info of a struct type from its runtime type, with
+// the offsets, sizes and alignments of the running architecture. Field
+// types are named as reflect prints them, qualified by package name rather
+// than import path.
+func AnalyseType(t reflect.Type) (Info, error) {
+	if t == nil || t.Kind() != reflect.Struct {
+		return Info{}, fmt.Errorf("%v is not a struct type", t)
+	}
+
+	var fields []Field
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		fields = append(fields, Field{
+			Name:     f.Name,
+			TypeName: f.Type.String(),
+			Kind:     reflectKind(f.Type),
+			Offset:   int64(f.Offset),
+			Size:     int64(f.Type.Size()),
+			Align:    int64(f.Type.FieldAlign()),
+		})
+	}
+
+	name := t.Name()
+	if name == "" {
+		name = t.String()
+	}
+
+	info := layoutInfo(name, padFields(fields, int64(t.Size())), optimizeFields(fields))
+	info.Package = t.PkgPath()
+	return info, nil
+}
+
+// AnalyseValue is AnalyseType for the type of v, a struct or a pointer to
+// one.
+func AnalyseValue(v any) (Info, error) {
+	t := reflect.TypeOf(v)
+	if t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return AnalyseType(t)
+}
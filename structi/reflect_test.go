@@ -0,0 +1,95 @@
+package structi
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"unsafe"
+)
+
+type reflected struct {
+	A  bool
+	B  int64
+	C  bool
+	mu sync.Mutex
+	S  []string
+}
+
+func TestAnalyseValue(t *testing.T) {
+	var v reflected
+	info, err := AnalyseValue(&v)
+	if err != nil {
+		t.Fatalf("analyse error: %v", err)
+	}
+
+	if info.Name != "reflected" || info.Package != "github.com/buarki/viztruct/structi" {
+		t.Errorf("unexpected name %s.%s", info.Package, info.Name)
+	}
+	if info.OriginalSize != int64(unsafe.Sizeof(v)) {
+		t.Errorf("got size %d, want %d", info.OriginalSize, unsafe.Sizeof(v))
+	}
+
+	offsets := map[string]uintptr{
+		"A":  unsafe.Offsetof(v.A),
+		"B":  unsafe.Offsetof(v.B),
+		"C":  unsafe.Offsetof(v.C),
+		"mu": unsafe.Offsetof(v.mu),
+		"S":  unsafe.Offsetof(v.S),
+	}
+	for _, f := range info.Fields {
+		if f.IsPadding {
+			continue
+		}
+		if want := int64(offsets[f.Name]); f.Offset != want {
+			t.Errorf("field %s at offset %d, want %d", f.Name, f.Offset, want)
+		}
+		if f.Name == "mu" && f.TypeName != "sync.Mutex" {
+			t.Errorf("got type %s for mu", f.TypeName)
+		}
+		if want := map[string]Kind{"A": KindBool, "B": KindInt, "C": KindBool, "mu": KindStruct, "S": KindSlice}[f.Name]; f.Kind != want {
+			t.Errorf("got kind %s for %s, want %s", f.Kind, f.Name, want)
+		}
+	}
+
+	// the layout matches the one computed from source for the same sizes
+	fromSource, err := AnalyseStructs(`type reflected struct {
+	A  bool
+	B  int64
+	C  bool
+	mu [8]byte
+	S  []string
+}
+`)
+	if err != nil {
+		t.Fatalf("analyse error: %v", err)
+	}
+	kinds := make(map[string]Kind)
+	for _, f := range info.Fields {
+		kinds[f.Name] = f.Kind
+	}
+	for _, f := range fromSource[0].Fields {
+		if !f.IsPadding && f.Name != "mu" && f.Kind != kinds[f.Name] {
+			t.Errorf("got kind %s for %s from source, %s from reflect", f.Kind, f.Name, kinds[f.Name])
+		}
+	}
+	if unsafe.Sizeof(uintptr(0)) == 8 && info.WastedBytes != fromSource[0].WastedBytes {
+		t.Errorf("got %d wasted bytes, source analysis found %d", info.WastedBytes, fromSource[0].WastedBytes)
+	}
+}
+
+func TestAnalyseTypeErrors(t *testing.T) {
+	if _, err := AnalyseType(reflect.TypeOf(42)); err == nil {
+		t.Errorf("int is not a struct")
+	}
+	if _, err := AnalyseValue(nil); err == nil {
+		t.Errorf("nil is not a struct")
+	}
+
+	info, err := AnalyseValue(struct{ A, B int32 }{})
+	if err != nil {
+		t.Fatalf("analyse error: %v", err)
+	}
+	if info.Name != "struct { A int32; B int32 }" || info.WastedBytes != 0 {
+		t.Errorf("unexpected info %+v", info)
+	}
+}
@@ -1,6 +1,8 @@
 package structi
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"go/ast"
 	"go/parser"
@@ -26,19 +28,33 @@ var (
 )
 
 type Info struct {
-	Name            string        `json:"name"`
-	Type            *types.Struct `json:"type,omitempty,omitzero"`
-	OriginalSize    int64         `json:"original_size"`
-	OptimizedSize   int64         `json:"optimized_size"`
-	WastedBytes     int64         `json:"wasted_bytes"`
-	WastedPercent   float64       `json:"wasted_percent"`
-	Fields          []Field       `json:"fields"`
-	OptimizedFields []Field       `json:"optimized_fields"`
+	Name            string               `json:"name"`
+	Package         string               `json:"package,omitempty"`
+	File            string               `json:"file,omitempty"`
+	Line            int                  `json:"line,omitempty"`
+	Column          int                  `json:"column,omitempty"`
+	EndLine         int                  `json:"end_line,omitempty"`
+	EndColumn       int                  `json:"end_column,omitempty"`
+	Type            *types.Struct        `json:"type,omitempty,omitzero"`
+	OriginalSize    int64                `json:"original_size"`
+	OptimizedSize   int64                `json:"optimized_size"`
+	WastedBytes     int64                `json:"wasted_bytes"`
+	WastedPercent   float64              `json:"wasted_percent"`
+	Fields          []Field              `json:"fields"`
+	OptimizedFields []Field              `json:"optimized_fields"`
+	AllocSites      []AllocSite          `json:"alloc_sites,omitempty"`
+	Escape          Escape               `json:"escape,omitempty"`
+	Registers       []RegisterAssignment `json:"registers,omitempty"`
+	Directives      []Directive          `json:"directives,omitempty"`
+	// BodyComments reports whether comments are written between the
+	// braces of the declaration, a rewritten declaration would lose them
+	BodyComments bool `json:"-"`
 }
 
 type Field struct {
 	Name      string `json:"name"`
 	TypeName  string `json:"type,omitempty,omitzero"`
+	Kind      Kind   `json:"kind,omitempty"`
 	Offset    int64  `json:"offset"`
 	Size      int64  `json:"size"`
 	Align     int64  `json:"align"`
@@ -57,6 +73,36 @@ func (i Info) TotalSize() int64 {
 	return last.Offset + last.Size
 }
 
+// QualifiedName returns the name of the struct prefixed by its package
+// path when known.
+func (i Info) QualifiedName() string {
+	if i.Package == "" {
+		return i.Name
+	}
+	return i.Package + "." + i.Name
+}
+
+// Align returns the alignment of the struct, the largest of its fields.
+func (i Info) Align() int64 {
+	align := int64(1)
+	for _, f := range i.Fields {
+		if !f.IsPadding && f.Align > align {
+			align = f.Align
+		}
+	}
+	return align
+}
+
+// LayoutHash identifies the layout of the struct, it changes whenever a
+// field is added, removed, retyped or moved.
+func (i Info) LayoutHash() string {
+	h := sha256.New()
+	for _, f := range i.Fields {
+		fmt.Fprintf(h, "%s %s %d %d\n", f.Name, f.TypeName, f.Offset, f.Size)
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
 func (i Info) OptimazedTotalSize() int64 {
 	if len(i.OptimizedFields) == 0 {
 		return 0
@@ -125,6 +171,7 @@ func (i Info) calculateLayout(structType *types.Struct, sizes types.Sizes) []Fie
 		fields = append(fields, Field{
 			Name:      field.Name(),
 			TypeName:  typeName(field.Type()),
+			Kind:      kindOf(field.Type()),
 			Offset:    offset,
 			Size:      size,
 			Align:     align,
@@ -159,32 +206,32 @@ func (i Info) calculateLayout(structType *types.Struct, sizes types.Sizes) []Fie
 }
 
 func (i Info) optimizeStructLayout(structType *types.Struct, sizes types.Sizes) []Field {
-	type fieldWithMeta struct {
-		name  string
-		typ   types.Type
-		size  int64
-		align int64
-	}
-
-	var fields []fieldWithMeta
+	var fields []Field
 	for i := 0; i < structType.NumFields(); i++ {
 		field := structType.Field(i)
-		size := sizes.Sizeof(field.Type())
-		align := sizes.Alignof(field.Type())
-		fields = append(fields, fieldWithMeta{
-			name:  field.Name(),
-			typ:   field.Type(),
-			size:  size,
-			align: align,
+		fields = append(fields, Field{
+			Name:     field.Name(),
+			TypeName: typeName(field.Type()),
+			Kind:     kindOf(field.Type()),
+			Size:     sizes.Sizeof(field.Type()),
+			Align:    sizes.Alignof(field.Type()),
 		})
 	}
 
+	return optimizeFields(fields)
+}
+
+// optimizeFields lays out the given fields, whose offsets are ignored,
+// in the order that minimizes padding.
+func optimizeFields(fields []Field) []Field {
+	fields = append([]Field(nil), fields...)
+
 	// sort fields by alignment (descending) and then by size (descending)
 	sort.Slice(fields, func(i, j int) bool {
-		if fields[i].align != fields[j].align {
-			return fields[i].align > fields[j].align
+		if fields[i].Align != fields[j].Align {
+			return fields[i].Align > fields[j].Align
 		}
-		return fields[i].size > fields[j].size
+		return fields[i].Size > fields[j].Size
 	})
 
 	// calculate offsets for optimized layout
@@ -193,8 +240,8 @@ func (i Info) optimizeStructLayout(structType *types.Struct, sizes types.Sizes)
 
 	for _, f := range fields {
 		// align field
-		if rem := offset % f.align; rem != 0 {
-			paddingSize := f.align - rem
+		if rem := offset % f.Align; rem != 0 {
+			paddingSize := f.Align - rem
 			optimizedFields = append(optimizedFields, Field{
 				Name:      "padding",
 				TypeName:  "",
@@ -206,23 +253,17 @@ func (i Info) optimizeStructLayout(structType *types.Struct, sizes types.Sizes)
 			offset += paddingSize
 		}
 
-		optimizedFields = append(optimizedFields, Field{
-			Name:      f.name,
-			TypeName:  typeName(f.typ),
-			Offset:    offset,
-			Size:      f.size,
-			Align:     f.align,
-			IsPadding: false,
-		})
+		f.Offset = offset
+		optimizedFields = append(optimizedFields, f)
 
-		offset += f.size
+		offset += f.Size
 	}
 
 	// add final padding for struct alignment
 	var structAlign int64 = 1
 	for _, f := range fields {
-		if f.align > structAlign {
-			structAlign = f.align
+		if f.Align > structAlign {
+			structAlign = f.Align
 		}
 	}
 
@@ -248,7 +289,7 @@ func AnalyseStructs(structsSource string) ([]Info, error) {
 	}
 
 	fset := token.NewFileSet()
-	node, err := parser.ParseFile(fset, "input.go", structsSource, parser.AllErrors)
+	node, err := parser.ParseFile(fset, "input.go", structsSource, parser.AllErrors|parser.ParseComments)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse input: %v", err)
 	}
@@ -268,20 +309,37 @@ func AnalyseStructs(structsSource string) ([]Info, error) {
 		return nil, &Error{fmt.Sprintf("failed to type-check: %v", err)}
 	}
 
-	return analyzeNestedStructs(node, &customSizes, info, fset)
+	structInfos, err := analyzeNestedStructs(node, &customSizes, info, fset)
+	if err != nil {
+		return nil, err
+	}
+
+	// the file name is made up, only the line is meaningful to callers
+	for i := range structInfos {
+		structInfos[i].File = ""
+	}
+
+	return structInfos, nil
 }
 
 func analyzeNestedStructs(node *ast.File, sizes types.Sizes, info *types.Info, fset *token.FileSet) ([]Info, error) {
 	var structInfos []Info
 
+	// a lone type declaration has its doc comment on the GenDecl
+	declDocs := make(map[*ast.TypeSpec]*ast.CommentGroup)
+
 	// find all struct declarations including nested ones
 	ast.Inspect(node, func(n ast.Node) bool {
+		if decl, ok := n.(*ast.GenDecl); ok && decl.Tok == token.TYPE && len(decl.Specs) == 1 {
+			declDocs[decl.Specs[0].(*ast.TypeSpec)] = decl.Doc
+		}
+
 		typeSpec, ok := n.(*ast.TypeSpec)
 		if !ok {
 			return true // continue traversing
 		}
 
-		_, ok = typeSpec.Type.(*ast.StructType)
+		structType, ok := typeSpec.Type.(*ast.StructType)
 		if !ok {
 			return true // not a struct, continue
 		}
@@ -292,40 +350,26 @@ func analyzeNestedStructs(node *ast.File, sizes types.Sizes, info *types.Info, f
 			return true // no type info available
 		}
 
+		// generic structs have no layout until instantiated
+		if named, ok := typeObj.Type().(*types.Named); ok && named.TypeParams().Len() > 0 {
+			return true
+		}
+
 		// get the underlying struct type
 		underlyingType, ok := typeObj.Type().Underlying().(*types.Struct)
 		if !ok {
 			return true // not a struct type
 		}
 
-		tempInfo := Info{}
-		fields := tempInfo.calculateLayout(underlyingType, sizes)
-		optimizedFields := tempInfo.optimizeStructLayout(underlyingType, sizes)
-
-		// calculate sizes using the fields directly
-		originalSize := int64(0)
-		if len(fields) > 0 {
-			last := fields[len(fields)-1]
-			originalSize = last.Offset + last.Size
-		}
-
-		optimizedSize := int64(0)
-		if len(optimizedFields) > 0 {
-			last := optimizedFields[len(optimizedFields)-1]
-			optimizedSize = last.Offset + last.Size
-		}
-
-		wastedBytes, wastedPercent := tempInfo.WastedSpace()
-
-		structInfo := Info{
-			Name:            typeSpec.Name.Name,
-			Type:            underlyingType,
-			OriginalSize:    originalSize,
-			OptimizedSize:   optimizedSize,
-			WastedBytes:     wastedBytes,
-			WastedPercent:   wastedPercent,
-			Fields:          fields,
-			OptimizedFields: optimizedFields,
+		structInfo := newInfo(typeSpec.Name.Name, underlyingType, sizes)
+		pos, end := fset.Position(typeSpec.Pos()), fset.Position(typeSpec.End())
+		structInfo.File, structInfo.Line, structInfo.Column = pos.Filename, pos.Line, pos.Column
+		structInfo.EndLine, structInfo.EndColumn = end.Line, end.Column
+		structInfo.Directives = parseDirectives(declDocs[typeSpec], typeSpec.Doc, typeSpec.Comment)
+		for _, group := range node.Comments {
+			if group.Pos() > structType.Fields.Opening && group.End() <= structType.Fields.Closing {
+				structInfo.BodyComments = true
+			}
 		}
 
 		structInfos = append(structInfos, structInfo)
@@ -334,3 +378,41 @@ func analyzeNestedStructs(node *ast.File, sizes types.Sizes, info *types.Info, f
 
 	return structInfos, nil
 }
+
+func newInfo(name string, structType *types.Struct, sizes types.Sizes) Info {
+	tempInfo := Info{}
+	fields := tempInfo.calculateLayout(structType, sizes)
+	optimizedFields := tempInfo.optimizeStructLayout(structType, sizes)
+
+	info := layoutInfo(name, fields, optimizedFields)
+	info.Type = structType
+	return info
+}
+
+// layoutInfo builds the Info of an already laid out struct.
+func layoutInfo(name string, fields, optimizedFields []Field) Info {
+	// calculate sizes using the fields directly
+	originalSize := int64(0)
+	if len(fields) > 0 {
+		last := fields[len(fields)-1]
+		originalSize = last.Offset + last.Size
+	}
+
+	optimizedSize := int64(0)
+	if len(optimizedFields) > 0 {
+		last := optimizedFields[len(optimizedFields)-1]
+		optimizedSize = last.Offset + last.Size
+	}
+
+	wastedBytes, wastedPercent := Info{Fields: fields}.WastedSpace()
+
+	return Info{
+		Name:            name,
+		OriginalSize:    originalSize,
+		OptimizedSize:   optimizedSize,
+		WastedBytes:     wastedBytes,
+		WastedPercent:   wastedPercent,
+		Fields:          fields,
+		OptimizedFields: optimizedFields,
+	}
+}
@@ -512,3 +512,56 @@ func TestAnalyseStructs(t *testing.T) {
 		})
 	}
 }
+
+func TestSizeClass(t *testing.T) {
+	tests := []struct {
+		size int64
+		want int64
+	}{
+		{size: 0, want: 0},
+		{size: 1, want: 8},
+		{size: 24, want: 24},
+		{size: 33, want: 48},
+		{size: 40, want: 48},
+		{size: 32768, want: 32768},
+		{size: 32769, want: 40960},
+	}
+
+	for _, tt := range tests {
+		if got := SizeClass(tt.size); got != tt.want {
+			t.Errorf("SizeClass(%d) = %d, want %d", tt.size, got, tt.want)
+		}
+	}
+}
+
+func TestBodyComments(t *testing.T) {
+	infos, err := AnalyseStructs(`
+// Plain has comments around it only.
+type Plain struct {
+	A bool
+	B int64
+} // trailing
+
+type Commented struct {
+	A bool // flag
+	B int64
+}
+
+type Floating struct {
+	A bool
+
+	// grouped below
+	B int64
+}
+`)
+	if err != nil {
+		t.Fatalf("analyse error: %v", err)
+	}
+
+	want := map[string]bool{"Plain": false, "Commented": true, "Floating": true}
+	for _, info := range infos {
+		if info.BodyComments != want[info.Name] {
+			t.Errorf("%s body comments = %v, want %v", info.Name, info.BodyComments, want[info.Name])
+		}
+	}
+}
@@ -0,0 +1,20 @@
+package structi
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FormatLayout renders fields one per line as the CLI text output does,
+// each line indented by two spaces.
+func FormatLayout(fields []Field) string {
+	var sb strings.Builder
+	for _, f := range fields {
+		if f.IsPadding {
+			fmt.Fprintf(&sb, "  [padding] %d bytes at offset %d\n", f.Size, f.Offset)
+		} else {
+			fmt.Fprintf(&sb, "  %s (%s) %d bytes at offset %d\n", f.Name, f.TypeName, f.Size, f.Offset)
+		}
+	}
+	return sb.String()
+}
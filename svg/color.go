@@ -0,0 +1,175 @@
+package svg
+
+import (
+	"strings"
+
+	"github.com/buarki/viztruct/structi"
+)
+
+const (
+	colorPadding     = "padding"
+	colorTailPadding = "tail_padding"
+	colorUnknown     = "unknown"
+
+	legendSwatch     = 14.0
+	legendLineHeight = 20.0
+	// space between the end of a legend label and the next swatch
+	legendGap = 20.0
+)
+
+// kindColors colors fields by the kind of their underlying type, padding
+// and types of unknown kind by their own keys, in the light theme.
+var kindColors = map[string]string{
+	string(structi.KindBool):      "#9C27B0", // purple
+	string(structi.KindInt):       "#4285F4", // blue
+	string(structi.KindFloat):     "#0097A7", // cyan
+	string(structi.KindComplex):   "#00BCD4", // light cyan
+	string(structi.KindString):    "#FF9800", // orange
+	string(structi.KindPointer):   "#EA4335", // red
+	string(structi.KindSlice):     "#34A853", // green
+	string(structi.KindMap):       "#FBBC05", // yellow
+	string(structi.KindChan):      "#E91E63", // pink
+	string(structi.KindFunc):      "#009688", // teal
+	string(structi.KindInterface): "#795548", // brown
+	string(structi.KindStruct):    "#607D8B", // blue gray
+	string(structi.KindArray):     "#3F51B5", // indigo
+	colorPadding:                  "#E0E0E0", // light gray for regular padding
+	colorTailPadding:              "#F5F5F5", // very light gray for tail padding
+	colorUnknown:                  "#AAAAAA", // default gray for unknown types
+}
+
+// legendOrder is the order of the legend entries and their labels.
+var legendOrder = []struct {
+	key   string
+	label string
+}{
+	{string(structi.KindBool), "bool"},
+	{string(structi.KindInt), "integer"},
+	{string(structi.KindFloat), "float"},
+	{string(structi.KindComplex), "complex"},
+	{string(structi.KindString), "string"},
+	{string(structi.KindPointer), "pointer"},
+	{string(structi.KindSlice), "slice"},
+	{string(structi.KindMap), "map"},
+	{string(structi.KindChan), "channel"},
+	{string(structi.KindFunc), "func"},
+	{string(structi.KindInterface), "interface"},
+	{string(structi.KindStruct), "struct"},
+	{string(structi.KindArray), "array"},
+	{colorPadding, "padding"},
+	{colorTailPadding, "tail padding"},
+	{colorUnknown, "other"},
+}
+
+type LegendEntry struct {
+	X     float64
+	Y     float64
+	Class string
+	Label string
+	// Hatched padding, as drawn by the grid view
+	Hatched bool
+}
+
+// colorKey returns the key of kindColors a field is drawn with, the end
+// of the struct telling tail padding apart.
+func colorKey(f structi.Field, totalSize int64) string {
+	if f.IsPadding {
+		if f.Offset+f.Size == totalSize {
+			return colorTailPadding
+		}
+		return colorPadding
+	}
+	kind := f.Kind
+	if kind == "" {
+		kind = kindFromName(f.TypeName)
+	}
+	if _, ok := kindColors[string(kind)]; ok && kind != "" {
+		return string(kind)
+	}
+	return colorUnknown
+}
+
+// fieldClass returns the class filling the block of a field with the
+// color of its kind in the theme.
+func fieldClass(f structi.Field, totalSize int64) string {
+	return "kind-" + colorKey(f, totalSize)
+}
+
+// kindFromName guesses the kind of fields read without type information,
+// by the Go spelling of their type.
+func kindFromName(typeName string) structi.Kind {
+	switch typeName {
+	case "bool":
+		return structi.KindBool
+	case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64", "uintptr", "byte", "rune":
+		return structi.KindInt
+	case "float32", "float64":
+		return structi.KindFloat
+	case "complex64", "complex128":
+		return structi.KindComplex
+	case "string":
+		return structi.KindString
+	case "unsafe.Pointer":
+		return structi.KindPointer
+	case "any", "error":
+		return structi.KindInterface
+	}
+
+	for _, p := range []struct {
+		prefix string
+		kind   structi.Kind
+	}{
+		{"*", structi.KindPointer},
+		{"[]", structi.KindSlice},
+		{"[", structi.KindArray},
+		{"map[", structi.KindMap},
+		{"chan ", structi.KindChan},
+		{"<-chan ", structi.KindChan},
+		{"func(", structi.KindFunc},
+		{"interface{", structi.KindInterface},
+		{"interface {", structi.KindInterface},
+		{"struct{", structi.KindStruct},
+		{"struct {", structi.KindStruct},
+	} {
+		if strings.HasPrefix(typeName, p.prefix) {
+			return p.kind
+		}
+	}
+	return ""
+}
+
+// legend lists the colors used by structs from y, wrapping the entries
+// at width. It returns the entries and the height they take.
+func legend(structs []structi.Info, y, width float64, hatched bool) ([]LegendEntry, float64) {
+	used := make(map[string]bool)
+	for _, info := range structs {
+		for _, fields := range [][]structi.Field{info.Fields, info.OptimizedFields} {
+			for _, f := range fields {
+				used[colorKey(f, info.TotalSize())] = true
+			}
+		}
+	}
+
+	var entries []LegendEntry
+	x := float64(paddingX)
+	lines := 0
+	for _, e := range legendOrder {
+		if !used[e.key] {
+			continue
+		}
+		entryWidth := legendSwatch + 6 + float64(len(e.label))*charWidth + legendGap
+		if lines == 0 || x+entryWidth > paddingX+width {
+			x = paddingX
+			lines++
+		}
+		entries = append(entries, LegendEntry{
+			X:       x,
+			Y:       y + float64(lines-1)*legendLineHeight,
+			Class:   "kind-" + e.key,
+			Label:   e.label,
+			Hatched: hatched && (e.key == colorPadding || e.key == colorTailPadding),
+		})
+		x += entryWidth
+	}
+	return entries, float64(lines) * legendLineHeight
+}
@@ -0,0 +1,144 @@
+package svg
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+
+	"github.com/buarki/viztruct/diff"
+	svgTemplate "github.com/buarki/viztruct/internal/viz/template"
+	"github.com/buarki/viztruct/structi"
+)
+
+const (
+	diffLineHeight = 15.0
+	// space taken by the name, summary, bars and offsets of a change
+	diffHeaderHeight = 165.0
+	diffChangeMargin = 30.0
+)
+
+type DiffLine struct {
+	Text    string
+	Changed bool
+}
+
+// DiffColumn is one side of a change: the layout at one revision.
+type DiffColumn struct {
+	Title  string
+	X      float64
+	EndX   float64
+	Size   int64
+	Blocks []FieldData
+	Lines  []DiffLine
+}
+
+type DiffData struct {
+	Name    string
+	Summary string
+	Y       float64
+	Columns []DiffColumn
+}
+
+type DiffTemplateData struct {
+	Width   float64
+	Height  float64
+	Changes []DiffData
+	Style   template.CSS
+}
+
+// BuildDiffVisualization draws the layouts of the changed structs at both
+// revisions side by side, both scaled to the bigger of the two so growth
+// is visible at a glance. The width, theme and style sheet of opts apply,
+// the other options only make sense for single layouts.
+func BuildDiffVisualization(changes []diff.Change, beforeRev, afterRev string, opts Options) (string, error) {
+	tmpl := template.New("svg_diff_template").Funcs(template.FuncMap{
+		"add":     func(a, b float64) float64 { return a + b },
+		"mul":     func(a, b float64) float64 { return a * b },
+		"float64": func(i int) float64 { return float64(i) },
+	})
+
+	tmpl, err := tmpl.Parse(svgTemplate.StructDiffTemplate)
+	if err != nil {
+		return "", fmt.Errorf("error parsing template: %v", err)
+	}
+
+	data := DiffTemplateData{Width: opts.Width, Style: style(opts)}
+	if data.Width <= 0 {
+		data.Width = defaultWidth
+	}
+	columnWidth := data.Width/2 - 2*paddingX
+	for _, c := range changes {
+		d := DiffData{Name: c.Struct, Summary: c.Summary(), Y: data.Height}
+
+		var scaleSize int64
+		for _, info := range []*structi.Info{c.Before, c.After} {
+			if info != nil && info.TotalSize() > scaleSize {
+				scaleSize = info.TotalSize()
+			}
+		}
+		scale := columnWidth
+		if scaleSize > 0 {
+			scale = columnWidth / float64(scaleSize)
+		}
+
+		changed := make(map[string]bool)
+		for _, f := range c.Fields {
+			changed[f.Name] = true
+		}
+
+		lines := 0
+		for i, side := range []struct {
+			rev  string
+			info *structi.Info
+		}{{beforeRev, c.Before}, {afterRev, c.After}} {
+			col := diffColumn(side.rev, side.info, paddingX+float64(i)*data.Width/2, scale, changed)
+			lines = max(lines, len(col.Lines))
+			d.Columns = append(d.Columns, col)
+		}
+
+		data.Changes = append(data.Changes, d)
+		data.Height += diffHeaderHeight + float64(lines)*diffLineHeight + diffChangeMargin
+	}
+
+	var result bytes.Buffer
+	result.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\" standalone=\"no\"?>\n")
+	if err := tmpl.ExecuteTemplate(&result, "struct_diff", data); err != nil {
+		return "", fmt.Errorf("error executing template: %v", err)
+	}
+	return result.String(), nil
+}
+
+func diffColumn(rev string, info *structi.Info, x, scale float64, changed map[string]bool) DiffColumn {
+	if info == nil {
+		return DiffColumn{Title: fmt.Sprintf("%s: not present", rev), X: x}
+	}
+
+	size := info.TotalSize()
+	col := DiffColumn{
+		Title: fmt.Sprintf("%s: %d bytes, %d wasted", rev, size, info.WastedBytes),
+		X:     x,
+		EndX:  x + float64(size)*scale,
+		Size:  size,
+	}
+
+	for _, f := range info.Fields {
+		col.Blocks = append(col.Blocks, FieldData{
+			Name:        f.Name,
+			X:           x + float64(f.Offset)*scale,
+			Width:       float64(f.Size) * scale,
+			Class:       fieldClass(f, size),
+			Offset:      f.Offset,
+			Size:        f.Size,
+			IsPadding:   f.IsPadding,
+			BlockHeight: float64(blockHeight),
+		})
+
+		text := fmt.Sprintf("[padding] %d bytes at offset %d", f.Size, f.Offset)
+		if !f.IsPadding {
+			text = fmt.Sprintf("%s (%s) %d bytes at offset %d", f.Name, f.TypeName, f.Size, f.Offset)
+		}
+		col.Lines = append(col.Lines, DiffLine{Text: text, Changed: !f.IsPadding && changed[f.Name]})
+	}
+
+	return col
+}
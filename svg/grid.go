@@ -0,0 +1,122 @@
+package svg
+
+import (
+	"fmt"
+
+	"github.com/buarki/viztruct/grid"
+	"github.com/buarki/viztruct/structi"
+)
+
+const (
+	gridRowHeight = 30.0
+	// room left of the cells for the row offsets
+	gridOffsetWidth = 60.0
+	// space between a grid and the title of the next one
+	gridMargin = 40.0
+)
+
+type GridColumnData struct {
+	X      float64
+	Offset int64
+}
+
+type GridRowData struct {
+	X      float64
+	Y      float64
+	Offset int64
+}
+
+type GridCellData struct {
+	X         float64
+	Y         float64
+	Width     float64
+	Height    float64
+	Name      string
+	Title     string
+	Class     string
+	IsPadding bool
+	// ShowName is unset when the name does not fit in the cell, it is
+	// still shown as a tooltip
+	ShowName bool
+}
+
+// GridData is one layout of a struct drawn as a memory grid.
+type GridData struct {
+	Title    string
+	TitleY   float64
+	ColumnsY float64
+	Columns  []GridColumnData
+	Rows     []GridRowData
+	Cells    []GridCellData
+}
+
+// prepareGridData draws the original and optimized layouts of info as
+// grids of rowBytes bytes per row.
+func prepareGridData(info structi.Info, width float64, rowBytes int64) TemplateData {
+	wastedBytes, wastedPercent := info.WastedSpace()
+	data := TemplateData{
+		Name:          info.Name,
+		TotalSize:     info.TotalSize(),
+		WastedBytes:   wastedBytes,
+		WastedPercent: wastedPercent,
+		OptimizedSize: info.OptimazedTotalSize(),
+	}
+
+	y := 90.0
+	for _, layout := range []struct {
+		title  string
+		fields []structi.Field
+	}{
+		{fmt.Sprintf("Original layout, %d bytes per row:", rowBytes), info.Fields},
+		{fmt.Sprintf("Optimized layout, %d bytes per row:", rowBytes), info.OptimizedFields},
+	} {
+		g := gridData(layout.title, layout.fields, info.TotalSize(), y, width, rowBytes)
+		data.Grids = append(data.Grids, g)
+		y = g.ColumnsY + 10 + float64(len(g.Rows))*gridRowHeight + gridMargin
+	}
+
+	data.Height = y - gridMargin + layoutMargin
+	return data
+}
+
+func gridData(title string, fields []structi.Field, totalSize int64, y, width float64, rowBytes int64) GridData {
+	g := GridData{Title: title, TitleY: y, ColumnsY: y + 20}
+
+	x := paddingX + gridOffsetWidth
+	cellWidth := (width - gridOffsetWidth) / float64(rowBytes)
+	// number every column when there is room, every eighth otherwise
+	step := int64(1)
+	if cellWidth < 3*charWidth {
+		step = 8
+	}
+	for col := int64(0); col < rowBytes; col += step {
+		g.Columns = append(g.Columns, GridColumnData{X: x + float64(col)*cellWidth + 2, Offset: col})
+	}
+
+	top := g.ColumnsY + 10
+	for i, row := range grid.Rows(fields, rowBytes) {
+		rowY := top + float64(i)*gridRowHeight
+		g.Rows = append(g.Rows, GridRowData{X: x - 8, Y: rowY + 19, Offset: row.Offset})
+
+		for _, c := range row.Cells {
+			cell := GridCellData{
+				X:         x + float64(c.Offset-row.Offset)*cellWidth,
+				Y:         rowY,
+				Width:     float64(c.Size) * cellWidth,
+				Height:    gridRowHeight,
+				Name:      c.Name,
+				IsPadding: c.IsPadding,
+			}
+			cell.ShowName = !c.IsPadding && float64(len(c.Name))*charWidth <= cell.Width-8
+
+			cell.Title = fmt.Sprintf("%s: %d bytes at offset %d", c.Name, c.Size, c.Offset)
+			if !c.IsPadding {
+				cell.Title = fmt.Sprintf("%s (%s): %d bytes at offset %d", c.Name, c.TypeName, c.Size, c.Offset)
+			}
+
+			cell.Class = fieldClass(structi.Field{TypeName: c.TypeName, Kind: c.Kind, Offset: c.Offset, Size: c.Size, IsPadding: c.IsPadding}, totalSize)
+			g.Cells = append(g.Cells, cell)
+		}
+	}
+	return g
+}
@@ -0,0 +1,57 @@
+package svg
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBuildGridDocument(t *testing.T) {
+	infos := analyse(t)
+
+	document, err := BuildDocument(infos, Options{Grid: 8})
+	if err != nil {
+		t.Fatalf("build error: %v", err)
+	}
+	if got := roots(t, document); len(got) != 1 || got[0] != "svg" {
+		t.Errorf("got roots %v, want a single svg", got)
+	}
+	for _, want := range []string{
+		"Original layout, 8 bytes per row:",
+		`class="hatched grid-padding"`,
+		"<title>B (int64): 8 bytes at offset 8</title>",
+	} {
+		if !strings.Contains(document, want) {
+			t.Errorf("missing %s", want)
+		}
+	}
+	if strings.Contains(document, "Suggested code:") {
+		t.Errorf("the grid view draws no bars")
+	}
+}
+
+func TestPrepareGridData(t *testing.T) {
+	// Entry is a string, a slice and a bool: 6 rows of 8 bytes, the last
+	// one holding the bool and its tail padding
+	entry := analyse(t)[1]
+	data := prepareGridData(entry, 1000, 8)
+
+	original := data.Grids[0]
+	if len(original.Rows) != 6 || len(original.Columns) != 8 {
+		t.Fatalf("got %d rows and %d columns", len(original.Rows), len(original.Columns))
+	}
+	last := original.Cells[len(original.Cells)-2:]
+	if last[0].Name != "Ok" || !last[0].ShowName || !last[1].IsPadding || last[1].Width != 7*last[0].Width {
+		t.Errorf("unexpected last row %+v", last)
+	}
+
+	optimized := data.Grids[1]
+	if optimized.TitleY <= original.Rows[len(original.Rows)-1].Y || data.Height <= optimized.Rows[len(optimized.Rows)-1].Y {
+		t.Errorf("grids overlap")
+	}
+
+	// a cache line row has no room for every column number nor long names
+	wide := prepareGridData(entry, 600, 64).Grids[0]
+	if len(wide.Columns) != 8 || wide.Cells[len(wide.Cells)-2].ShowName {
+		t.Errorf("got %d columns on a cache line", len(wide.Columns))
+	}
+}
@@ -0,0 +1,266 @@
+package svg
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/buarki/viztruct/structi"
+)
+
+// Scale is how the bytes of a struct map to the width of its blocks.
+type Scale string
+
+const (
+	// ScaleLinear draws blocks proportional to their size.
+	ScaleLinear Scale = "linear"
+	// ScaleMinWidth is linear but never draws a field narrower than
+	// Options.MinBlockWidth.
+	ScaleMinWidth Scale = "min-width"
+	// ScaleLog draws blocks proportional to the logarithm of their size.
+	ScaleLog Scale = "log"
+	// ScaleWrap draws Options.RowBytes bytes per row, linearly, splitting
+	// the fields that cross a row boundary.
+	ScaleWrap Scale = "wrap"
+
+	defaultMinBlockWidth = 12.0
+	defaultRowBytes      = 64
+
+	// horizontal room taken by a rotated label
+	labelSpacing = 16.0
+	// space between the bottom of a row and the labels of the next one,
+	// leaving room for the offsets
+	rowGap = 25.0
+)
+
+func ParseScale(s string) (Scale, error) {
+	switch Scale(s) {
+	case "", ScaleLinear:
+		return ScaleLinear, nil
+	case ScaleMinWidth, ScaleLog, ScaleWrap:
+		return Scale(s), nil
+	}
+	return "", fmt.Errorf("invalid scale: %s. use 'linear', 'min-width', 'log' or 'wrap'", s)
+}
+
+// bars places the blocks of the original and the optimized layouts of a
+// struct, both drawn with the same scale so they can be compared.
+type bars struct {
+	scale Scale
+	width float64
+	// pixels per byte, or per log unit with ScaleLog
+	unit     float64
+	minWidth float64
+	rowBytes int64
+	rows     int
+}
+
+func newBars(info structi.Info, width float64, opts Options) bars {
+	b := bars{scale: opts.Scale, width: width, minWidth: opts.MinBlockWidth, rowBytes: opts.RowBytes, rows: 1}
+	if b.scale == "" {
+		b.scale = ScaleLinear
+	}
+	if b.minWidth <= 0 {
+		b.minWidth = defaultMinBlockWidth
+	}
+	if b.rowBytes <= 0 {
+		b.rowBytes = defaultRowBytes
+	}
+
+	size := info.TotalSize()
+	switch b.scale {
+	case ScaleWrap:
+		b.unit = width / float64(b.rowBytes)
+		b.rows = max(1, int((size+b.rowBytes-1)/b.rowBytes))
+	case ScaleLog:
+		var units float64
+		for _, f := range info.Fields {
+			units += logUnits(f.Size)
+		}
+		if units > 0 {
+			b.unit = width / units
+		}
+	case ScaleMinWidth:
+		b.unit = minWidthUnit(info.Fields, width, b.minWidth)
+	default:
+		b.unit = width
+		if size > 0 {
+			b.unit = width / float64(size)
+		}
+	}
+	return b
+}
+
+func logUnits(size int64) float64 {
+	if size <= 0 {
+		return 0
+	}
+	return 1 + math.Log2(float64(size))
+}
+
+// minWidthUnit returns the pixels per byte that fill width once the
+// fields narrower than minWidth are widened to it. The fields overflow
+// width when there are too many of them to be minWidth wide.
+func minWidthUnit(fields []structi.Field, width, minWidth float64) float64 {
+	var size int64
+	for _, f := range fields {
+		size += f.Size
+	}
+	if size == 0 {
+		return 0
+	}
+
+	// widening the narrow fields shrinks the others, which may get
+	// narrower than minWidth in turn
+	unit := width / float64(size)
+	for range fields {
+		var widened, rest float64
+		var restBytes int64
+		for _, f := range fields {
+			if f.Size > 0 && float64(f.Size)*unit < minWidth {
+				widened += minWidth
+			} else {
+				restBytes += f.Size
+			}
+		}
+		rest = width - widened
+		if restBytes == 0 || rest <= 0 {
+			return 0
+		}
+		next := rest / float64(restBytes)
+		if next == unit {
+			break
+		}
+		unit = next
+	}
+	return unit
+}
+
+func (b bars) blockWidth(size int64) float64 {
+	switch b.scale {
+	case ScaleLog:
+		return logUnits(size) * b.unit
+	case ScaleMinWidth:
+		if size > 0 {
+			return max(float64(size)*b.unit, b.minWidth)
+		}
+	}
+	return float64(size) * b.unit
+}
+
+// pitch is the vertical distance between two rows whose labels are
+// labels tall.
+func pitch(labels float64) float64 {
+	return blockHeight + rowGap + 2*labelMargin + labels
+}
+
+// place lays out fields, the first row of blocks starting at y. It
+// returns the blocks and where the bar ends.
+func (b bars) place(fields []structi.Field, y, labels float64, class func(structi.Field) string) ([]FieldData, float64, float64) {
+	var blocks []FieldData
+	x := float64(paddingX)
+	for _, f := range fields {
+		field := FieldData{
+			Name:        f.Name,
+			Class:       class(f),
+			Offset:      f.Offset,
+			Size:        f.Size,
+			IsPadding:   f.IsPadding,
+			BlockHeight: float64(blockHeight),
+		}
+
+		if b.scale == ScaleWrap {
+			for i, s := range b.span(f.Offset, f.Size, y, labels) {
+				s.Name, s.Class, s.Offset, s.Size, s.IsPadding = field.Name, field.Class, field.Offset, field.Size, field.IsPadding
+				s.Label = i == 0
+				blocks = append(blocks, s)
+			}
+			continue
+		}
+
+		field.X, field.Y, field.Width, field.Label = x, y, b.blockWidth(f.Size), true
+		x += field.Width
+		blocks = append(blocks, field)
+	}
+
+	placeLabels(blocks, paddingX, paddingX+b.width)
+
+	if b.scale == ScaleWrap && len(fields) > 0 {
+		last := fields[len(fields)-1]
+		endX, endY := b.position(last.Offset+last.Size, y, labels)
+		return blocks, endX, endY
+	}
+	return blocks, x, y
+}
+
+// span returns the blocks of size bytes from offset, one per row they
+// cross.
+func (b bars) span(offset, size int64, y, labels float64) []FieldData {
+	var blocks []FieldData
+	for {
+		row := offset / b.rowBytes
+		n := min(size, (row+1)*b.rowBytes-offset)
+		blocks = append(blocks, FieldData{
+			X:           paddingX + float64(offset%b.rowBytes)*b.unit,
+			Y:           y + float64(row)*pitch(labels),
+			Width:       float64(n) * b.unit,
+			BlockHeight: float64(blockHeight),
+		})
+		offset, size = offset+n, size-n
+		if size <= 0 {
+			return blocks
+		}
+	}
+}
+
+// position returns where offset falls in the wrapped rows, an offset on
+// a row boundary ends the previous row.
+func (b bars) position(offset int64, y, labels float64) (float64, float64) {
+	row := offset / b.rowBytes
+	col := offset % b.rowBytes
+	if col == 0 && row > 0 {
+		row, col = row-1, b.rowBytes
+	}
+	return paddingX + float64(col)*b.unit, y + float64(row)*pitch(labels)
+}
+
+// placeLabels spreads the labels of each row at least labelSpacing apart,
+// as close as possible to the middle of their block, between left and
+// right. Labels moved away from their block get a leader line.
+func placeLabels(blocks []FieldData, left, right float64) {
+	rows := make(map[float64][]int)
+	var order []float64
+	for i, block := range blocks {
+		if !block.Label {
+			continue
+		}
+		if _, ok := rows[block.Y]; !ok {
+			order = append(order, block.Y)
+		}
+		rows[block.Y] = append(rows[block.Y], i)
+	}
+
+	for _, y := range order {
+		row := rows[y]
+		xs := make([]float64, len(row))
+		for i, b := range row {
+			blocks[b].AnchorX = blocks[b].X + blocks[b].Width/2
+			xs[i] = blocks[b].AnchorX
+			if i > 0 {
+				xs[i] = max(xs[i], xs[i-1]+labelSpacing)
+			}
+		}
+		// pushed past the right edge, slide back left as far as the room
+		// between left and right allows
+		for i := len(xs) - 1; i >= 0; i-- {
+			limit := right
+			if i < len(xs)-1 {
+				limit = xs[i+1] - labelSpacing
+			}
+			xs[i] = max(min(xs[i], limit), left)
+		}
+		for i, b := range row {
+			blocks[b].LabelX = xs[i]
+			blocks[b].Leader = math.Abs(xs[i]-blocks[b].AnchorX) > 0.5
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package svg
+
+import (
+	"math"
+	"testing"
+
+	"github.com/buarki/viztruct/structi"
+)
+
+func analyseLarge(t *testing.T) structi.Info {
+	t.Helper()
+	infos, err := structi.AnalyseStructs(`
+type Large struct {
+	Ok     bool
+	Buffer [500]byte
+	Count  int64
+	A, B, C, D bool
+}
+`)
+	if err != nil {
+		t.Fatalf("analyse error: %v", err)
+	}
+	return infos[0]
+}
+
+func barWidth(blocks []FieldData) float64 {
+	var width float64
+	for _, b := range blocks {
+		width += b.Width
+	}
+	return width
+}
+
+func TestParseScale(t *testing.T) {
+	for _, s := range []string{"", "linear", "min-width", "log", "wrap"} {
+		if _, err := ParseScale(s); err != nil {
+			t.Errorf("unexpected error for %q: %v", s, err)
+		}
+	}
+	if _, err := ParseScale("square"); err == nil {
+		t.Errorf("expected an error for an unknown scale")
+	}
+}
+
+func TestScaleMinWidth(t *testing.T) {
+	info := analyseLarge(t)
+	data := prepareTemplateData(info, 1000, Options{Scale: ScaleMinWidth, MinBlockWidth: 20})
+
+	for _, f := range data.Fields {
+		if f.Size > 0 && f.Width < 20-1e-9 {
+			t.Errorf("field %s is %v wide", f.Name, f.Width)
+		}
+	}
+	if w := barWidth(data.Fields); math.Abs(w-1000) > 1e-6 {
+		t.Errorf("got bar width %v, want 1000", w)
+	}
+
+	linear := prepareTemplateData(info, 1000, Options{})
+	if linear.Fields[0].Width >= 20 {
+		t.Errorf("the bool is %v wide on a linear scale", linear.Fields[0].Width)
+	}
+}
+
+func TestScaleLog(t *testing.T) {
+	data := prepareTemplateData(analyseLarge(t), 1000, Options{Scale: ScaleLog})
+
+	if w := barWidth(data.Fields); math.Abs(w-1000) > 1e-6 {
+		t.Errorf("got bar width %v, want 1000", w)
+	}
+	// Ok bool, Buffer [500]byte: the buffer is wider, not 500 times wider
+	if ratio := data.Fields[1].Width / data.Fields[0].Width; ratio < 2 || ratio > 20 {
+		t.Errorf("got width ratio %v between Buffer and Ok", ratio)
+	}
+}
+
+func TestScaleWrap(t *testing.T) {
+	info := analyseLarge(t)
+	data := prepareTemplateData(info, 640, Options{Scale: ScaleWrap, RowBytes: 64})
+
+	var segments []FieldData
+	for _, f := range data.Fields {
+		if f.Name == "Buffer" {
+			segments = append(segments, f)
+		}
+		if f.X+f.Width > paddingX+640+1e-9 {
+			t.Errorf("field %s overflows its row", f.Name)
+		}
+	}
+	// Buffer spans bytes 1 to 501, rows 0 to 7
+	if len(segments) != 8 || !segments[0].Label || segments[1].Label {
+		t.Fatalf("got %d Buffer segments", len(segments))
+	}
+	if segments[0].Width != 63*10 || segments[7].Width != 53*10 {
+		t.Errorf("got segments %v and %v wide", segments[0].Width, segments[7].Width)
+	}
+
+	rows := (info.TotalSize() + 63) / 64
+	if data.BarsEndY-data.BarY != float64(rows-1)*pitch(labelsHeight(info.Fields)) {
+		t.Errorf("got bars from %v to %v for %d rows", data.BarY, data.BarsEndY, rows)
+	}
+	if linear := prepareTemplateData(info, 640, Options{}); data.Height <= linear.Height {
+		t.Errorf("wrapped rows should take more room than a single bar")
+	}
+}
+
+func TestPlaceLabels(t *testing.T) {
+	infos, err := structi.AnalyseStructs(`
+type Flags struct {
+	Counter [1000]byte
+	A, B, C, D, E bool
+}
+`)
+	if err != nil {
+		t.Fatalf("analyse error: %v", err)
+	}
+	data := prepareTemplateData(infos[0], 1000, Options{})
+
+	for i := 1; i < len(data.Fields); i++ {
+		if gap := data.Fields[i].LabelX - data.Fields[i-1].LabelX; gap < labelSpacing-1e-9 {
+			t.Errorf("labels of %s and %s are %v apart", data.Fields[i-1].Name, data.Fields[i].Name, gap)
+		}
+		if data.Fields[i].LabelX > paddingX+1000 {
+			t.Errorf("label of %s is past the right edge", data.Fields[i].Name)
+		}
+	}
+	if data.Fields[0].Leader || !data.Fields[len(data.Fields)-2].Leader {
+		t.Errorf("only the moved labels need a leader")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"html/template"
+	"strings"
 
 	svgTemplate "github.com/buarki/viztruct/internal/viz/template"
 	"github.com/buarki/viztruct/structi"
@@ -12,34 +13,64 @@ import (
 const (
 	blockHeight = 40
 	paddingX    = 10
+
+	defaultWidth = 1200.0
+
+	// approximate advance of a character of the 14px field labels
+	charWidth  = 7.5
+	lineHeight = 15.0
+	// space between a section title and the rotated labels below it, and
+	// between those labels and the bar
+	labelMargin = 20.0
+	// blank space below the last line of a struct
+	layoutMargin = 30.0
+
+	tocHeaderHeight = 40.0
+	tocLineHeight   = 20.0
+	tocMargin       = 20.0
 )
 
-var typeColors = map[string]string{
-	"uint64":       "#4285F4", // blue
-	"uint32":       "#34A853", // green
-	"uint16":       "#FBBC05", // yellow
-	"uint8":        "#EA4335", // red
-	"int64":        "#4285F4", // blue
-	"int32":        "#34A853", // green
-	"int16":        "#FBBC05", // yellow
-	"int8":         "#EA4335", // red
-	"bool":         "#9C27B0", // purple
-	"string":       "#FF9800", // orange
-	"byte":         "#607D8B", // blue gray
-	"rune":         "#795548", // brown
-	"float64":      "#0097A7", // cyan
-	"float32":      "#00BCD4", // light cyan
-	"padding":      "#E0E0E0", // light gray for regular padding
-	"tail_padding": "#F5F5F5", // very light gray for tail padding
-	"unknown":      "#AAAAAA", // default gray for unknown types
+// Options tune the documents built by BuildDocument and BuildDocuments.
+type Options struct {
+	// Width of the document in pixels, 1200 when zero.
+	Width float64
+
+	// TOC lists the structs at the top of the document, each entry
+	// linking to the layout of its struct.
+	TOC bool
+
+	// Scale of the blocks, ScaleLinear when empty.
+	Scale Scale
+	// MinBlockWidth is the narrowest block drawn with ScaleMinWidth, 12
+	// pixels when zero.
+	MinBlockWidth float64
+	// RowBytes is the number of bytes per row with ScaleWrap, 64 when
+	// zero.
+	RowBytes int64
+
+	// Grid draws each struct as rows of Grid bytes, like a memory dump,
+	// instead of bars when positive.
+	Grid int64
+
+	// Theme colors the document, LightTheme when zero.
+	Theme Theme
+	// CSS is appended to the style sheet of the theme, overriding its
+	// classes: .background, .field-text, .block, .kind-pointer...
+	CSS string
 }
 
 type FieldData struct {
-	Name        string
-	LabelX      float64
+	Name   string
+	LabelX float64
+	// AnchorX is the middle of the block, LabelX moves away from it when
+	// labels would collide, with a Leader line back to the block
+	AnchorX     float64
+	Leader      bool
+	Label       bool
 	X           float64
+	Y           float64
 	Width       float64
-	Color       string
+	Class       string
 	Offset      int64
 	Size        int64
 	IsPadding   bool
@@ -61,21 +92,144 @@ type TemplateData struct {
 	OptimizedWastePercent float64
 	Fields                []FieldData
 	OptimizedFields       []FieldData
+	SavedBlocks           []FieldData
 	FieldBreakdown        []FieldBreakdownData
 	OptimizedFieldsCode   []string
+	Grids                 []GridData
 	LastOffsetX           float64
+	LastOffsetY           float64
 	OptimizedLastX        float64
+	OptimizedLastY        float64
+	SavedLastX            float64
+	SavedLastY            float64
 	BlockHeight           float64
+	// vertical positions of the sections, relative to the top of the
+	// struct, computed from the field count, label lengths and rows
+	BarY              float64
+	BarsEndY          float64
+	OptimizedTitleY   float64
+	OptimizedBarY     float64
+	OptimizedBarsEndY float64
+	Height            float64
+	// ID anchors the group of the struct in the document
+	ID string
+	Y  float64
 }
 
-func getTypeColor(typeName string) string {
-	if color, ok := typeColors[typeName]; ok {
-		return color
-	}
-	return typeColors["unknown"]
+type TOCEntry struct {
+	ID   string
+	Text string
+	Y    float64
+}
+
+// DocumentData is a whole SVG document: the layouts of its structs
+// stacked vertically, below the optional table of contents.
+type DocumentData struct {
+	Width   float64
+	Height  float64
+	TOC     []TOCEntry
+	Legend  []LegendEntry
+	Structs []TemplateData
+	Style   template.CSS
 }
 
+// BuildVisualization draws the layouts of structs into a single SVG
+// document.
 func BuildVisualization(structs []structi.Info) (string, error) {
+	return BuildDocument(structs, Options{})
+}
+
+// BuildDocument draws the layouts of structs into a single SVG document,
+// one group per struct stacked vertically.
+func BuildDocument(structs []structi.Info, opts Options) (string, error) {
+	tmpl, err := parseLayoutTemplate()
+	if err != nil {
+		return "", err
+	}
+
+	data := DocumentData{Width: opts.Width, Style: style(opts)}
+	if data.Width <= 0 {
+		data.Width = defaultWidth
+	}
+	ids := AnchorIDs(structs)
+
+	if opts.TOC && len(structs) > 0 {
+		data.Height = tocHeaderHeight
+		for i, structInfo := range structs {
+			data.Height += tocLineHeight
+			data.TOC = append(data.TOC, TOCEntry{
+				ID:   ids[i],
+				Text: fmt.Sprintf("%s: %d bytes, %d wasted", structInfo.QualifiedName(), structInfo.TotalSize(), structInfo.WastedBytes),
+				Y:    data.Height,
+			})
+		}
+		data.Height += tocMargin
+	}
+
+	width := data.Width - (2 * paddingX)
+	if len(structs) > 0 {
+		var height float64
+		data.Legend, height = legend(structs, data.Height+legendSwatch+10, width, opts.Grid > 0)
+		data.Height += height + 10
+	}
+
+	for i, structInfo := range structs {
+		var layout TemplateData
+		if opts.Grid > 0 {
+			layout = prepareGridData(structInfo, width, opts.Grid)
+		} else {
+			layout = prepareTemplateData(structInfo, width, opts)
+		}
+		layout.ID, layout.Y = ids[i], data.Height
+		data.Structs = append(data.Structs, layout)
+		data.Height += layout.Height
+	}
+
+	var result bytes.Buffer
+	result.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\" standalone=\"no\"?>\n")
+	if err := tmpl.ExecuteTemplate(&result, "struct_layouts", data); err != nil {
+		return "", fmt.Errorf("error executing template: %v", err)
+	}
+	return result.String(), nil
+}
+
+// BuildDocuments draws each struct into its own SVG document, in the
+// order of structs.
+func BuildDocuments(structs []structi.Info, opts Options) ([]string, error) {
+	var documents []string
+	for _, structInfo := range structs {
+		document, err := BuildDocument([]structi.Info{structInfo}, opts)
+		if err != nil {
+			return nil, err
+		}
+		documents = append(documents, document)
+	}
+	return documents, nil
+}
+
+// AnchorIDs returns a distinct XML id for each struct, derived from its
+// qualified name, usable as a fragment or a file name.
+func AnchorIDs(structs []structi.Info) []string {
+	seen := make(map[string]int)
+	var ids []string
+	for _, structInfo := range structs {
+		id := "struct-" + strings.Map(func(r rune) rune {
+			if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '_' {
+				return r
+			}
+			return '-'
+		}, structInfo.QualifiedName())
+
+		seen[id]++
+		if n := seen[id]; n > 1 {
+			id = fmt.Sprintf("%s-%d", id, n)
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func parseLayoutTemplate() (*template.Template, error) {
 	tmpl := template.New("svg_template").Funcs(template.FuncMap{
 		"add": func(a, b float64) float64 { return a + b },
 		"sub": func(a, b float64) float64 { return a - b },
@@ -95,91 +249,22 @@ func BuildVisualization(structs []structi.Info) (string, error) {
 		"lt": func(a, b int64) bool { return a < b },
 	})
 
-	tmpl, err := tmpl.Parse(svgTemplate.StructLayoutTemplate)
-	if err != nil {
-		return "", fmt.Errorf("error parsing template: %v", err)
-	}
-
-	var result bytes.Buffer
-	result.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\" standalone=\"no\"?>\n")
-
-	width := 1200.0 - (2 * paddingX)
-	for _, structInfo := range structs {
-		data := prepareTemplateData(structInfo, width)
-		err = tmpl.ExecuteTemplate(&result, "struct_layout", data)
-		if err != nil {
-			return "", fmt.Errorf("error executing template: %v", err)
+	for _, src := range []string{svgTemplate.StructLayoutTemplate, svgTemplate.StructGridTemplate} {
+		if _, err := tmpl.Parse(src); err != nil {
+			return nil, fmt.Errorf("error parsing template: %v", err)
 		}
 	}
-
-	return result.String(), nil
+	return tmpl, nil
 }
 
-func prepareTemplateData(info structi.Info, width float64) TemplateData {
+func prepareTemplateData(info structi.Info, width float64, opts Options) TemplateData {
 	wastedBytes, wastedPercent := info.WastedSpace()
 	_, optimizedWastedPercent := info.OptimazedWastedSpace()
 	structTotalSize := info.TotalSize()
 	optimizedSize := info.OptimazedTotalSize()
 
-	scale := width / float64(structTotalSize)
-	if structTotalSize == 0 {
-		scale = width // to avoid division by zero
-	}
-
-	var fields []FieldData
-	for _, f := range info.Fields {
-		blockX := paddingX + float64(f.Offset)*scale
-		blockWidth := float64(f.Size) * scale
-
-		color := getTypeColor(f.TypeName)
-		if f.IsPadding {
-			if f.Offset+f.Size == structTotalSize {
-				color = getTypeColor("tail_padding")
-			} else {
-				color = getTypeColor("padding")
-			}
-		}
-
-		field := FieldData{
-			Name:        f.Name,
-			LabelX:      blockX + blockWidth/2,
-			X:           blockX,
-			Width:       blockWidth,
-			Color:       color,
-			Offset:      f.Offset,
-			Size:        f.Size,
-			IsPadding:   f.IsPadding,
-			BlockHeight: float64(blockHeight),
-		}
-		fields = append(fields, field)
-	}
-
-	var optimizedFields []FieldData
-	for _, f := range info.OptimizedFields {
-		blockX := paddingX + float64(f.Offset)*scale
-		blockWidth := float64(f.Size) * scale
-
-		color := getTypeColor(f.TypeName)
-		if f.IsPadding {
-			if f.Offset+f.Size == structTotalSize {
-				color = getTypeColor("tail_padding")
-			} else {
-				color = getTypeColor("padding")
-			}
-		}
-
-		field := FieldData{
-			Name:        f.Name,
-			LabelX:      blockX + blockWidth/2,
-			X:           blockX,
-			Width:       blockWidth,
-			Color:       color,
-			Offset:      f.Offset,
-			Size:        f.Size,
-			IsPadding:   f.IsPadding,
-			BlockHeight: float64(blockHeight),
-		}
-		optimizedFields = append(optimizedFields, field)
+	class := func(f structi.Field) string {
+		return fieldClass(f, structTotalSize)
 	}
 
 	var fieldBreakdown []FieldBreakdownData
@@ -201,7 +286,38 @@ func prepareTemplateData(info structi.Info, width float64) TemplateData {
 		}
 	}
 
+	b := newBars(info, width, opts)
+
+	// the rotated labels hang above the bars, as long as the longest name
+	labels := labelsHeight(info.Fields)
+	barY := 90 + labelMargin + labels + labelMargin
+	fields, lastX, lastY := b.place(info.Fields, barY, labels, class)
+	barsEndY := barY + float64(b.rows-1)*pitch(labels)
+
+	optimizedTitleY := barsEndY + 120 + float64(len(fieldBreakdown))*lineHeight + 25
+	optimizedLabels := labelsHeight(info.OptimizedFields)
+	optimizedBarY := optimizedTitleY + labelMargin + optimizedLabels + labelMargin
+	optimizedFields, optimizedLastX, optimizedLastY := b.place(info.OptimizedFields, optimizedBarY, optimizedLabels, class)
+	optimizedBarsEndY := optimizedBarY + float64(b.rows-1)*pitch(optimizedLabels)
+
+	// the bytes reordering saves, drawn after the optimized bar up to the
+	// original size
+	var saved []FieldData
+	savedLastX, savedLastY := lastX, optimizedBarY
+	if b.scale == ScaleWrap {
+		saved = b.span(optimizedSize, structTotalSize-optimizedSize, optimizedBarY, optimizedLabels)
+		savedLastX, savedLastY = b.position(structTotalSize, optimizedBarY, optimizedLabels)
+	} else if lastX > optimizedLastX {
+		saved = []FieldData{{X: optimizedLastX, Y: optimizedBarY, Width: lastX - optimizedLastX, BlockHeight: float64(blockHeight)}}
+	}
+
 	return TemplateData{
+		BarY:                  barY,
+		BarsEndY:              barsEndY,
+		OptimizedTitleY:       optimizedTitleY,
+		OptimizedBarY:         optimizedBarY,
+		OptimizedBarsEndY:     optimizedBarsEndY,
+		Height:                optimizedBarsEndY + 135 + float64(len(optimizedFieldsCode))*lineHeight + layoutMargin,
 		Name:                  info.Name,
 		TotalSize:             structTotalSize,
 		WastedBytes:           wastedBytes,
@@ -211,10 +327,23 @@ func prepareTemplateData(info structi.Info, width float64) TemplateData {
 		OptimizedWastePercent: optimizedWastedPercent,
 		Fields:                fields,
 		OptimizedFields:       optimizedFields,
+		SavedBlocks:           saved,
 		FieldBreakdown:        fieldBreakdown,
 		OptimizedFieldsCode:   optimizedFieldsCode,
-		LastOffsetX:           paddingX + float64(structTotalSize)*scale,
-		OptimizedLastX:        paddingX + float64(optimizedSize)*scale,
+		LastOffsetX:           lastX,
+		LastOffsetY:           lastY,
+		OptimizedLastX:        optimizedLastX,
+		OptimizedLastY:        optimizedLastY,
+		SavedLastX:            savedLastX,
+		SavedLastY:            savedLastY,
 		BlockHeight:           float64(blockHeight),
 	}
 }
+
+func labelsHeight(fields []structi.Field) float64 {
+	longest := 0
+	for _, f := range fields {
+		longest = max(longest, len(f.Name))
+	}
+	return float64(longest) * charWidth
+}
@@ -0,0 +1,230 @@
+package svg
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/buarki/viztruct/structi"
+)
+
+// roots returns the names of the top level elements of an XML document,
+// failing the test when it is not well formed.
+func roots(t *testing.T, document string) []string {
+	t.Helper()
+	decoder := xml.NewDecoder(strings.NewReader(document))
+	var names []string
+	depth := 0
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("invalid document: %v", err)
+		}
+		switch tok := token.(type) {
+		case xml.StartElement:
+			if depth == 0 {
+				names = append(names, tok.Name.Local)
+			}
+			depth++
+		case xml.EndElement:
+			depth--
+		}
+	}
+	return names
+}
+
+func analyse(t *testing.T) []structi.Info {
+	t.Helper()
+	infos, err := structi.AnalyseStructs(`
+type Header struct {
+	A bool
+	B int64
+}
+
+type Entry struct {
+	Key   string
+	Value []byte
+	Ok    bool
+}
+`)
+	if err != nil {
+		t.Fatalf("analyse error: %v", err)
+	}
+	return infos
+}
+
+func TestBuildDocument(t *testing.T) {
+	infos := analyse(t)
+
+	document, err := BuildDocument(infos, Options{TOC: true})
+	if err != nil {
+		t.Fatalf("build error: %v", err)
+	}
+
+	if got := roots(t, document); len(got) != 1 || got[0] != "svg" {
+		t.Errorf("got roots %v, want a single svg", got)
+	}
+	for _, want := range []string{
+		`<g id="struct-Header" transform="translate(0 130)">`,
+		`<g id="struct-Entry" transform="translate(0 827.5)">`,
+		`href="#struct-Entry"`,
+	} {
+		if !strings.Contains(document, want) {
+			t.Errorf("missing %s", want)
+		}
+	}
+
+	document, err = BuildVisualization(infos)
+	if err != nil {
+		t.Fatalf("build error: %v", err)
+	}
+	if strings.Contains(document, `id="toc"`) || !strings.Contains(document, `<svg width="1200"`) {
+		t.Errorf("unexpected table of contents or width without options")
+	}
+}
+
+func TestBuildDocumentSize(t *testing.T) {
+	infos, err := structi.AnalyseStructs(`
+type Small struct {
+	A bool
+}
+
+type Large struct {
+	A, B, C, D, E, F, G, H, I, J, K, L, M, N, O, P, Q, R, S, T, U, V, W, X, Y, Z bool
+	AVeryLongFieldNameThatNeedsRoomAboveTheBar                                    int64
+}
+`)
+	if err != nil {
+		t.Fatalf("analyse error: %v", err)
+	}
+
+	small := prepareTemplateData(infos[0], defaultWidth, Options{})
+	large := prepareTemplateData(infos[1], defaultWidth, Options{})
+	if small.Height >= 600 {
+		t.Errorf("got height %v for a single field", small.Height)
+	}
+	// each field adds a breakdown and a suggested code line
+	if large.Height < small.Height+2*26*lineHeight {
+		t.Errorf("got height %v for 27 fields", large.Height)
+	}
+	// the labels of the long name fit between the title and the bar
+	if large.BarY-small.BarY != float64(len("AVeryLongFieldNameThatNeedsRoomAboveTheBar")-len("A"))*charWidth {
+		t.Errorf("got bar at %v, %v for short names", large.BarY, small.BarY)
+	}
+
+	document, err := BuildDocument(infos, Options{Width: 800})
+	if err != nil {
+		t.Fatalf("build error: %v", err)
+	}
+	want := fmt.Sprintf(`<svg width="800" height="%v"`, small.Height+large.Height+legendLineHeight+10)
+	if !strings.Contains(document, want) {
+		t.Errorf("missing %s", want)
+	}
+}
+
+func TestBuildDocuments(t *testing.T) {
+	documents, err := BuildDocuments(analyse(t), Options{})
+	if err != nil {
+		t.Fatalf("build error: %v", err)
+	}
+	if len(documents) != 2 {
+		t.Fatalf("got %d documents, want 2", len(documents))
+	}
+	for _, document := range documents {
+		if got := roots(t, document); len(got) != 1 {
+			t.Errorf("got roots %v, want a single svg", got)
+		}
+	}
+	if !strings.Contains(documents[1], `id="struct-Entry"`) {
+		t.Errorf("second document does not draw Entry")
+	}
+}
+
+func TestAnchorIDs(t *testing.T) {
+	ids := AnchorIDs([]structi.Info{
+		{Name: "Entry", Package: "example.com/app"},
+		{Name: "Entry", Package: "example.com/app"},
+		{Name: "Entry"},
+	})
+	want := []string{"struct-example.com-app.Entry", "struct-example.com-app.Entry-2", "struct-Entry"}
+	for i := range want {
+		if ids[i] != want[i] {
+			t.Errorf("got id %s, want %s", ids[i], want[i])
+		}
+	}
+}
+
+func TestColors(t *testing.T) {
+	infos, err := structi.AnalyseStructs(`
+type Duration int64
+
+type User struct{}
+
+type Kinds struct {
+	P *User
+	B []byte
+	M map[string]int
+	D Duration
+	I int
+	U uintptr
+	E error
+	S struct{ A int32 }
+	A [4]uint16
+}
+`)
+	if err != nil {
+		t.Fatalf("analyse error: %v", err)
+	}
+	kinds := infos[len(infos)-1]
+
+	want := map[string]string{
+		"P": "kind-pointer",
+		"B": "kind-slice",
+		"M": "kind-map",
+		"D": "kind-int",
+		"I": "kind-int",
+		"U": "kind-int",
+		"E": "kind-interface",
+		"S": "kind-struct",
+		"A": "kind-array",
+	}
+	for _, f := range kinds.Fields {
+		if f.IsPadding {
+			continue
+		}
+		if got := fieldClass(f, kinds.TotalSize()); got != want[f.Name] {
+			t.Errorf("field %s (%s) colored %s, want %s", f.Name, f.TypeName, got, want[f.Name])
+		}
+		// fields read without type information fall back to their name
+		f.Kind = ""
+		if got := fieldClass(f, kinds.TotalSize()); f.Name != "D" && got != want[f.Name] {
+			t.Errorf("field %s (%s) colored %s by name, want %s", f.Name, f.TypeName, got, want[f.Name])
+		}
+	}
+
+	// named types are not literals, whatever their package is called
+	for _, typeName := range []string{"structpb.Value", "interfaces.Foo"} {
+		f := structi.Field{Name: "V", TypeName: typeName, Size: 8}
+		if got := fieldClass(f, 8); got != "kind-"+colorUnknown {
+			t.Errorf("field of type %s colored %s by name, want kind-%s", typeName, got, colorUnknown)
+		}
+	}
+
+	document, err := BuildVisualization([]structi.Info{kinds})
+	if err != nil {
+		t.Fatalf("build error: %v", err)
+	}
+	for _, label := range []string{">pointer<", ">slice<", ">map<", ">integer<", ">interface<", ">struct<", ">array<"} {
+		if !strings.Contains(document, label) {
+			t.Errorf("legend misses %s", label)
+		}
+	}
+	if strings.Contains(document, ">float<") {
+		t.Errorf("legend lists a kind no field has")
+	}
+}
@@ -0,0 +1,227 @@
+package svg
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"maps"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Theme holds the colors of a document. Fields are filled with the
+// colors of their kind, keyed like kindColors.
+type Theme struct {
+	Name        string            `json:"base,omitempty"`
+	Background  string            `json:"background,omitempty"`
+	Text        string            `json:"text,omitempty"`
+	Muted       string            `json:"muted,omitempty"`
+	Link        string            `json:"link,omitempty"`
+	Accent      string            `json:"accent,omitempty"`
+	Outline     string            `json:"outline,omitempty"`
+	StrokeWidth float64           `json:"stroke_width,omitempty"`
+	Colors      map[string]string `json:"colors,omitempty"`
+}
+
+var (
+	LightTheme = Theme{
+		Name:        "light",
+		Background:  "white",
+		Text:        "#000000",
+		Muted:       "gray",
+		Link:        "#0000EE",
+		Accent:      "#FF0000",
+		Outline:     "black",
+		StrokeWidth: 1,
+		Colors:      kindColors,
+	}
+
+	DarkTheme = Theme{
+		Name:        "dark",
+		Background:  "#1E1E1E",
+		Text:        "#E0E0E0",
+		Muted:       "#888888",
+		Link:        "#8AB4F8",
+		Accent:      "#FF6B6B",
+		Outline:     "#BBBBBB",
+		StrokeWidth: 1,
+		Colors: withColors(kindColors, map[string]string{
+			colorPadding:     "#3A3A3A",
+			colorTailPadding: "#2C2C2C",
+			colorUnknown:     "#777777",
+		}),
+	}
+
+	HighContrastTheme = Theme{
+		Name:        "high-contrast",
+		Background:  "#000000",
+		Text:        "#FFFFFF",
+		Muted:       "#FFFFFF",
+		Link:        "#FFFF00",
+		Accent:      "#FFFF00",
+		Outline:     "#FFFFFF",
+		StrokeWidth: 2,
+		Colors: map[string]string{
+			"bool":           "#FF00FF",
+			"int":            "#0066FF",
+			"float":          "#00CCCC",
+			"complex":        "#66FFFF",
+			"string":         "#FF8000",
+			"pointer":        "#FF0000",
+			"slice":          "#00CC00",
+			"map":            "#FFFF00",
+			"chan":           "#FF66CC",
+			"func":           "#00FF99",
+			"interface":      "#CC6600",
+			"struct":         "#9999FF",
+			"array":          "#6600FF",
+			colorPadding:     "#000000",
+			colorTailPadding: "#000000",
+			colorUnknown:     "#BBBBBB",
+		},
+	}
+
+	// ColorblindSafeTheme uses the Okabe-Ito palette, telling kinds apart
+	// with any color vision deficiency, and lighter tints of it past its
+	// eight colors.
+	ColorblindSafeTheme = Theme{
+		Name:        "colorblind-safe",
+		Background:  "white",
+		Text:        "#000000",
+		Muted:       "gray",
+		Link:        "#0072B2",
+		Accent:      "#D55E00",
+		Outline:     "black",
+		StrokeWidth: 1,
+		Colors: withColors(kindColors, map[string]string{
+			"bool":      "#CC79A7",
+			"int":       "#0072B2",
+			"float":     "#56B4E9",
+			"complex":   "#AAD9F4",
+			"string":    "#E69F00",
+			"pointer":   "#D55E00",
+			"slice":     "#009E73",
+			"map":       "#F0E442",
+			"chan":      "#E5BCD3",
+			"func":      "#7FCEB9",
+			"interface": "#F2CF7F",
+			"struct":    "#7FB8D8",
+			"array":     "#EAAE7F",
+		}),
+	}
+
+	themes = []Theme{LightTheme, DarkTheme, HighContrastTheme, ColorblindSafeTheme}
+)
+
+func withColors(base, colors map[string]string) map[string]string {
+	merged := maps.Clone(base)
+	maps.Copy(merged, colors)
+	return merged
+}
+
+// ThemeNames lists the names of the builtin themes.
+func ThemeNames() []string {
+	var names []string
+	for _, t := range themes {
+		names = append(names, t.Name)
+	}
+	return names
+}
+
+// ThemeByName returns a builtin theme, the light one for an empty name.
+func ThemeByName(name string) (Theme, error) {
+	if name == "" {
+		return LightTheme, nil
+	}
+	for _, t := range themes {
+		if t.Name == name {
+			return t, nil
+		}
+	}
+	return Theme{}, fmt.Errorf("unknown theme: %s. use %s", name, strings.Join(ThemeNames(), ", "))
+}
+
+// ReadPalette reads a JSON palette file overriding the colors of a
+// builtin theme, the one named by its "base" key or else by base:
+//
+//	{"base": "dark", "background": "#002B36", "colors": {"pointer": "#DC322F"}}
+func ReadPalette(path, base string) (Theme, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Theme{}, fmt.Errorf("error reading palette: %v", err)
+	}
+
+	var palette Theme
+	if err := json.Unmarshal(data, &palette); err != nil {
+		return Theme{}, fmt.Errorf("error decoding palette: %v", err)
+	}
+
+	if palette.Name == "" {
+		palette.Name = base
+	}
+	theme, err := ThemeByName(palette.Name)
+	if err != nil {
+		return Theme{}, err
+	}
+	theme.Colors = withColors(theme.Colors, palette.Colors)
+	for _, c := range []struct {
+		dst *string
+		src string
+	}{
+		{&theme.Background, palette.Background},
+		{&theme.Text, palette.Text},
+		{&theme.Muted, palette.Muted},
+		{&theme.Link, palette.Link},
+		{&theme.Accent, palette.Accent},
+		{&theme.Outline, palette.Outline},
+	} {
+		if c.src != "" {
+			*c.dst = c.src
+		}
+	}
+	if palette.StrokeWidth > 0 {
+		theme.StrokeWidth = palette.StrokeWidth
+	}
+	return theme, nil
+}
+
+// CSS returns the style sheet of the theme. Values are written as given,
+// palettes are trusted input.
+func (t Theme) CSS() string {
+	if t.Colors == nil {
+		t = LightTheme
+	}
+
+	var sb strings.Builder
+	font := "font-family: Arial, sans-serif;"
+	fmt.Fprintf(&sb, ".background { fill: %s; }\n", t.Background)
+	fmt.Fprintf(&sb, ".field-text { %s font-size: 14px; fill: %s; }\n", font, t.Text)
+	fmt.Fprintf(&sb, ".struct-name { %s font-size: 16px; font-weight: bold; fill: %s; }\n", font, t.Text)
+	fmt.Fprintf(&sb, ".offset-text { %s font-size: 12px; fill: %s; }\n", font, t.Text)
+	fmt.Fprintf(&sb, ".link-text { %s font-size: 14px; fill: %s; }\n", font, t.Link)
+	fmt.Fprintf(&sb, ".padding-text, .changed-text { %s font-size: 14px; fill: %s; }\n", font, t.Accent)
+	fmt.Fprintf(&sb, ".block { stroke: %s; stroke-width: %g; }\n", t.Outline, t.StrokeWidth)
+	fmt.Fprintf(&sb, ".padding-block { stroke: %s; stroke-width: %g; stroke-dasharray: 5,5; }\n", t.Muted, t.StrokeWidth)
+	fmt.Fprintf(&sb, ".grid-padding, .swatch { stroke: %s; stroke-width: %g; }\n", t.Muted, t.StrokeWidth)
+	fmt.Fprintf(&sb, ".leader { stroke: %s; stroke-width: 1; }\n", t.Muted)
+	fmt.Fprintf(&sb, ".hatched { fill: url(#hatch); }\n")
+	fmt.Fprintf(&sb, ".hatch-background { fill: %s; }\n", t.Colors[colorTailPadding])
+	fmt.Fprintf(&sb, ".hatch-line { stroke: %s; stroke-width: 2; }\n", t.Muted)
+
+	keys := make([]string, 0, len(t.Colors))
+	for key := range t.Colors {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(&sb, ".kind-%s { fill: %s; }\n", key, t.Colors[key])
+	}
+	return sb.String()
+}
+
+// style is the content of the <style> element of a document: the theme
+// followed by the user style sheet, which wins over it.
+func style(opts Options) template.CSS {
+	return template.CSS(opts.Theme.CSS() + opts.CSS)
+}
@@ -0,0 +1,110 @@
+package svg
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/buarki/viztruct/diff"
+)
+
+func TestThemeByName(t *testing.T) {
+	for _, name := range ThemeNames() {
+		theme, err := ThemeByName(name)
+		if err != nil || theme.Name != name {
+			t.Errorf("got %v, %v for %s", theme.Name, err, name)
+		}
+		for _, e := range legendOrder {
+			if theme.Colors[e.key] == "" {
+				t.Errorf("theme %s has no color for %s", name, e.key)
+			}
+		}
+	}
+	if theme, _ := ThemeByName(""); theme.Name != "light" {
+		t.Errorf("got %s by default", theme.Name)
+	}
+	if _, err := ThemeByName("solarized"); err == nil {
+		t.Errorf("expected an error for an unknown theme")
+	}
+}
+
+func TestReadPalette(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "palette.json")
+	if err := os.WriteFile(path, []byte(`{"base": "dark", "background": "#002B36", "colors": {"pointer": "#DC322F"}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	theme, err := ReadPalette(path, "light")
+	if err != nil {
+		t.Fatalf("read error: %v", err)
+	}
+	if theme.Background != "#002B36" || theme.Text != DarkTheme.Text {
+		t.Errorf("unexpected theme %+v", theme)
+	}
+	if theme.Colors["pointer"] != "#DC322F" || theme.Colors["slice"] != DarkTheme.Colors["slice"] {
+		t.Errorf("unexpected colors %v", theme.Colors)
+	}
+	if DarkTheme.Colors["pointer"] == "#DC322F" || kindColors["pointer"] == "#DC322F" {
+		t.Errorf("the palette changed the builtin colors")
+	}
+
+	if err := os.WriteFile(path, []byte(`{"base": "sepia"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadPalette(path, "light"); err == nil {
+		t.Errorf("expected an error for an unknown base theme")
+	}
+
+	// without a base key the palette changes the theme it is given
+	if err := os.WriteFile(path, []byte(`{"accent": "#B58900"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	theme, err = ReadPalette(path, "high-contrast")
+	if err != nil {
+		t.Fatalf("read error: %v", err)
+	}
+	if theme.Accent != "#B58900" || theme.Background != HighContrastTheme.Background {
+		t.Errorf("unexpected theme %+v", theme)
+	}
+}
+
+func TestDocumentTheme(t *testing.T) {
+	document, err := BuildDocument(analyse(t), Options{Theme: DarkTheme, CSS: ".background { fill: #101010; }"})
+	if err != nil {
+		t.Fatalf("build error: %v", err)
+	}
+
+	for _, want := range []string{
+		".background { fill: #1E1E1E; }",
+		".field-text { font-family: Arial, sans-serif; font-size: 14px; fill: #E0E0E0; }",
+		".kind-padding { fill: #3A3A3A; }",
+	} {
+		if !strings.Contains(document, want) {
+			t.Errorf("missing %s", want)
+		}
+	}
+	// the user style sheet comes last to win over the theme
+	if theme, user := strings.Index(document, "fill: #1E1E1E"), strings.Index(document, "fill: #101010"); user < theme {
+		t.Errorf("user css at %d, before the theme at %d", user, theme)
+	}
+	if strings.Contains(document, `fill="#000000"`) || strings.Contains(document, `fill="white"`) {
+		t.Errorf("colors are hardcoded out of the style sheet")
+	}
+}
+
+func TestDiffTheme(t *testing.T) {
+	infos := analyse(t)
+	changes := []diff.Change{{Struct: "Header", Before: &infos[0], After: &infos[1]}}
+
+	document, err := BuildDiffVisualization(changes, "v1", "v2", Options{Width: 800, Theme: DarkTheme})
+	if err != nil {
+		t.Fatalf("build error: %v", err)
+	}
+	if !strings.Contains(document, `<svg width="800"`) {
+		t.Errorf("width option ignored")
+	}
+	if !strings.Contains(document, ".background { fill: #1E1E1E; }") {
+		t.Errorf("theme option ignored")
+	}
+}
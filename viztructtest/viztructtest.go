@@ -0,0 +1,114 @@
+// Package viztructtest asserts struct layouts from go test, using the
+// offsets of the architecture the tests run on:
+//
+//	func TestLayout(t *testing.T) {
+//		viztructtest.AssertNoWaste(t, Session{})
+//		viztructtest.AssertMaxSize(t, Entry{}, 64)
+//		viztructtest.AssertFieldsOnSameCacheLine(t, Counter{}, "hits", "misses")
+//	}
+//
+// On failure the current layout and the optimized one are printed.
+package viztructtest
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/buarki/viztruct/lint"
+	"github.com/buarki/viztruct/structi"
+)
+
+// CacheLineSize is the cache line size AssertFieldsOnSameCacheLine checks
+// against.
+var CacheLineSize = lint.DefaultConfig().CacheLineSize
+
+// AssertNoWaste fails when reordering the fields of v, a struct or a
+// pointer to one, would make it smaller. Padding no order can avoid, like
+// the tail padding of struct{ int64; bool }, is accepted.
+func AssertNoWaste(t testing.TB, v any) bool {
+	t.Helper()
+	info, ok := analyse(t, v)
+	if !ok {
+		return false
+	}
+	if info.OptimizedSize < info.OriginalSize {
+		t.Errorf("%s wastes %d bytes (%.2f%%), reordering its fields saves %d bytes\n%s",
+			info.Name, info.WastedBytes, info.WastedPercent, info.OriginalSize-info.OptimizedSize, layout(info))
+		return false
+	}
+	return true
+}
+
+// AssertMaxSize fails when v, a struct or a pointer to one, is bigger
+// than max bytes.
+func AssertMaxSize(t testing.TB, v any, max int64) bool {
+	t.Helper()
+	info, ok := analyse(t, v)
+	if !ok {
+		return false
+	}
+	if info.OriginalSize > max {
+		t.Errorf("%s is %d bytes, over the %d bytes limit (%d bytes once reordered)\n%s",
+			info.Name, info.OriginalSize, max, info.OptimizedSize, layout(info))
+		return false
+	}
+	return true
+}
+
+// AssertFieldsOnSameCacheLine fails when the named fields of v, a struct
+// or a pointer to one, do not all fit in the same CacheLineSize bytes
+// line, assuming the struct starts on a line boundary.
+func AssertFieldsOnSameCacheLine(t testing.TB, v any, names ...string) bool {
+	t.Helper()
+	info, ok := analyse(t, v)
+	if !ok {
+		return false
+	}
+
+	fields := make(map[string]structi.Field)
+	for _, f := range info.Fields {
+		if !f.IsPadding {
+			fields[f.Name] = f
+		}
+	}
+
+	line := int64(-1)
+	var lines []string
+	spread := false
+	for _, name := range names {
+		f, ok := fields[name]
+		if !ok {
+			t.Errorf("%s has no field %s", info.Name, name)
+			return false
+		}
+		first, last := f.Offset/CacheLineSize, (f.Offset+max(f.Size, 1)-1)/CacheLineSize
+		if first != last || (line >= 0 && first != line) {
+			spread = true
+		}
+		line = first
+		lines = append(lines, fmt.Sprintf("%s on line %d", name, first))
+	}
+
+	if spread {
+		t.Errorf("%s fields are not on the same %d bytes cache line: %s\n%s",
+			info.Name, CacheLineSize, strings.Join(lines, ", "), layout(info))
+		return false
+	}
+	return true
+}
+
+func analyse(t testing.TB, v any) (structi.Info, bool) {
+	t.Helper()
+	info, err := structi.AnalyseValue(v)
+	if err != nil {
+		t.Errorf("%v", err)
+		return structi.Info{}, false
+	}
+	return info, true
+}
+
+func layout(info structi.Info) string {
+	return "\nOriginal Layout:\n" + structi.FormatLayout(info.Fields) +
+		"\nOptimized Layout:\n" + structi.FormatLayout(info.OptimizedFields)
+}
@@ -0,0 +1,82 @@
+package viztructtest
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// recorder captures the failures of an assertion instead of failing the
+// test running it.
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+type padded struct {
+	A bool
+	B int64
+	C bool
+}
+
+type packed struct {
+	B int64
+	A bool
+	C bool
+}
+
+type counters struct {
+	hits   int64
+	_      [56]byte
+	misses int64
+	total  int64
+}
+
+func TestAssertNoWaste(t *testing.T) {
+	AssertNoWaste(t, packed{})
+	AssertNoWaste(t, &packed{})
+
+	r := &recorder{TB: t}
+	if AssertNoWaste(r, padded{}) || len(r.errors) != 1 {
+		t.Fatalf("expected a failure, got %v", r.errors)
+	}
+	for _, want := range []string{"padded wastes 14 bytes", "reordering its fields saves 8 bytes", "Original Layout:", "Optimized Layout:", "  B (int64) 8 bytes at offset 0"} {
+		if !strings.Contains(r.errors[0], want) {
+			t.Errorf("missing %q in\n%s", want, r.errors[0])
+		}
+	}
+}
+
+func TestAssertMaxSize(t *testing.T) {
+	AssertMaxSize(t, packed{}, 16)
+
+	r := &recorder{TB: t}
+	if AssertMaxSize(r, padded{}, 16) || !strings.Contains(strings.Join(r.errors, ""), "padded is 24 bytes, over the 16 bytes limit (16 bytes once reordered)") {
+		t.Errorf("unexpected failures %v", r.errors)
+	}
+
+	r = &recorder{TB: t}
+	if AssertMaxSize(r, 42, 16) || len(r.errors) != 1 {
+		t.Errorf("expected an error for a non struct, got %v", r.errors)
+	}
+}
+
+func TestAssertFieldsOnSameCacheLine(t *testing.T) {
+	AssertFieldsOnSameCacheLine(t, counters{}, "misses", "total")
+
+	r := &recorder{TB: t}
+	if AssertFieldsOnSameCacheLine(r, counters{}, "hits", "misses") || !strings.Contains(strings.Join(r.errors, ""), "hits on line 0, misses on line 1") {
+		t.Errorf("unexpected failures %v", r.errors)
+	}
+
+	r = &recorder{TB: t}
+	if AssertFieldsOnSameCacheLine(r, counters{}, "hits", "nope") || !strings.Contains(strings.Join(r.errors, ""), "counters has no field nope") {
+		t.Errorf("unexpected failures %v", r.errors)
+	}
+}